@@ -0,0 +1,216 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// App is an interactive terminal interface for the todo API
+type App struct {
+	ctx        context.Context
+	controller *termdash.Controller
+	drawCh     chan struct{}
+	term       *tcell.Terminal
+	size       image.Point
+	client     Client
+	interval   time.Duration
+	widgets    *widgets
+
+	mu    sync.Mutex
+	model *model
+}
+
+// New creates the interface. The list is refreshed from client every
+// interval
+func New(client Client, interval time.Duration) (*App, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a := &App{
+		ctx:      ctx,
+		drawCh:   make(chan struct{}, 1),
+		client:   client,
+		interval: interval,
+		model:    &model{status: "Loading..."},
+	}
+
+	keys := func(k *terminalapi.Keyboard) {
+		a.mu.Lock()
+		act := a.model.key(k.Key)
+		a.mu.Unlock()
+
+		switch act.op {
+		case opQuit:
+			cancel()
+			return
+		case opNone:
+		default:
+			go a.run(act)
+		}
+		a.draw()
+	}
+
+	w, err := newWidgets()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	term, err := tcell.New()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c, err := newGrid(w, term)
+	if err != nil {
+		cancel()
+		term.Close()
+		return nil, err
+	}
+
+	controller, err := termdash.NewController(term, c,
+		termdash.KeyboardSubscriber(keys))
+	if err != nil {
+		cancel()
+		term.Close()
+		return nil, err
+	}
+
+	a.controller = controller
+	a.term = term
+	a.widgets = w
+
+	return a, nil
+}
+
+// draw asks the Run loop to render the current state. Requests made
+// while a render is pending are merged
+func (a *App) draw() {
+	select {
+	case a.drawCh <- struct{}{}:
+	default:
+	}
+}
+
+func (a *App) render() error {
+	// leave room for the borders of the list
+	rows := a.term.Size().Y*listHeightPerc/100 - 2
+
+	a.mu.Lock()
+	lines := a.model.lines(rows)
+	prompt := a.model.prompt()
+	status := a.model.status
+	a.mu.Unlock()
+
+	if err := a.widgets.update(lines, prompt, status); err != nil {
+		return err
+	}
+
+	return a.controller.Redraw()
+}
+
+// run sends act to the API and reloads the list
+func (a *App) run(act action) {
+	var (
+		err error
+		msg string
+	)
+
+	switch act.op {
+	case opAdd:
+		err = a.client.Add(act.task)
+		msg = fmt.Sprintf("Added task %q to the list.", act.task)
+	case opComplete:
+		err = a.client.Complete(act.id)
+		msg = fmt.Sprintf("Item number %d marked as completed.", act.id)
+	case opDelete:
+		err = a.client.Delete(act.id)
+		msg = fmt.Sprintf("Item number %d deleted.", act.id)
+	}
+
+	if err != nil {
+		a.setStatus(fmt.Sprintf("Error: %s", err))
+		return
+	}
+
+	a.refresh(msg)
+}
+
+// refresh reloads the list from the API and shows msg once done
+func (a *App) refresh(msg string) {
+	items, err := a.client.List()
+
+	a.mu.Lock()
+	if err != nil {
+		a.model.status = fmt.Sprintf("Error: %s", err)
+	} else {
+		a.model.setItems(items)
+		if msg == "" {
+			msg = fmt.Sprintf("Updated at %s", time.Now().Format("15:04:05"))
+		}
+		a.model.status = msg
+	}
+	a.mu.Unlock()
+
+	a.draw()
+}
+
+func (a *App) setStatus(msg string) {
+	a.mu.Lock()
+	a.model.status = msg
+	a.mu.Unlock()
+
+	a.draw()
+}
+
+func (a *App) resize() error {
+	if a.size.Eq(a.term.Size()) {
+		return nil
+	}
+
+	a.size = a.term.Size()
+	if err := a.term.Clear(); err != nil {
+		return err
+	}
+
+	return a.render()
+}
+
+// Run shows the interface until the user quits
+func (a *App) Run() error {
+	defer a.term.Close()
+	defer a.controller.Close()
+
+	resizeTicker := time.NewTicker(2 * time.Second)
+	defer resizeTicker.Stop()
+
+	refreshTicker := time.NewTicker(a.interval)
+	defer refreshTicker.Stop()
+
+	go a.refresh("")
+	a.draw()
+
+	for {
+		select {
+		case <-a.drawCh:
+			if err := a.render(); err != nil {
+				return err
+			}
+		case <-a.ctx.Done():
+			return nil
+		case <-refreshTicker.C:
+			go a.refresh("")
+		case <-resizeTicker.C:
+			if err := a.resize(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/container/grid"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// listHeightPerc is the share of the terminal used by the list
+const listHeightPerc = 80
+
+func newGrid(w *widgets, t terminalapi.Terminal) (*container.Container, error) {
+	builder := grid.New()
+
+	builder.Add(
+		grid.RowHeightPerc(listHeightPerc,
+			grid.Widget(w.txtList,
+				container.Border(linestyle.Light),
+				container.BorderTitle("Todo - Press Q to Quit"),
+			),
+		),
+	)
+
+	builder.Add(
+		grid.RowHeightPerc(10,
+			grid.Widget(w.txtPrompt, container.Border(linestyle.Light)),
+		),
+	)
+
+	builder.Add(
+		grid.RowHeightPerc(10,
+			grid.Widget(w.txtStatus, container.Border(linestyle.Light)),
+		),
+	)
+
+	gridOpts, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := container.New(t, gridOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,238 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+// Item is a todo item as shown in the interface
+type Item struct {
+	Task        string
+	Done        bool
+	CreatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Client is the part of the todo API used by the interface
+type Client interface {
+	List() ([]Item, error)
+	Add(task string) error
+	Complete(id int) error
+	Delete(id int) error
+}
+
+type mode int
+
+const (
+	modeList mode = iota
+	modeAdd
+	modeFilter
+)
+
+const (
+	opNone     = ""
+	opAdd      = "add"
+	opComplete = "complete"
+	opDelete   = "delete"
+	opRefresh  = "refresh"
+	opQuit     = "quit"
+)
+
+// action is a request triggered by a key press
+type action struct {
+	op   string
+	id   int
+	task string
+}
+
+// entry is an item in the list along with its ID in the API
+type entry struct {
+	id int
+	Item
+}
+
+// line is a single row of the rendered list
+type line struct {
+	text     string
+	selected bool
+}
+
+// model holds the state of the interface. It doesn't touch the terminal
+// or the API so key handling can be tested on its own
+type model struct {
+	items  []Item
+	filter string
+	cursor int
+	offset int
+	mode   mode
+	input  []rune
+	status string
+}
+
+// visible returns the items that match the current filter
+func (m *model) visible() []entry {
+	entries := []entry{}
+	f := strings.ToLower(m.filter)
+
+	for k, i := range m.items {
+		if f == "" || strings.Contains(strings.ToLower(i.Task), f) {
+			entries = append(entries, entry{id: k + 1, Item: i})
+		}
+	}
+
+	return entries
+}
+
+// setItems replaces the list, keeping the cursor within bounds
+func (m *model) setItems(items []Item) {
+	m.items = items
+	m.clamp()
+}
+
+func (m *model) clamp() {
+	n := len(m.visible())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func (m *model) selected() (entry, bool) {
+	v := m.visible()
+	if len(v) == 0 {
+		return entry{}, false
+	}
+	return v[m.cursor], true
+}
+
+// key updates the model for a key press and returns the action to run
+func (m *model) key(k keyboard.Key) action {
+	if m.mode != modeList {
+		return m.inputKey(k)
+	}
+
+	switch k {
+	case keyboard.KeyArrowUp, 'k':
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case keyboard.KeyArrowDown, 'j':
+		m.cursor++
+		m.clamp()
+	case 'a':
+		m.mode = modeAdd
+		m.input = nil
+	case '/':
+		m.mode = modeFilter
+		m.input = []rune(m.filter)
+	case keyboard.KeyEsc:
+		m.filter = ""
+		m.clamp()
+	case 'c':
+		if e, ok := m.selected(); ok {
+			return action{op: opComplete, id: e.id, task: e.Task}
+		}
+	case 'd':
+		if e, ok := m.selected(); ok {
+			return action{op: opDelete, id: e.id, task: e.Task}
+		}
+	case 'r':
+		return action{op: opRefresh}
+	case 'q', 'Q':
+		return action{op: opQuit}
+	}
+
+	return action{}
+}
+
+// inputKey handles typing in the add and filter prompts
+func (m *model) inputKey(k keyboard.Key) action {
+	switch k {
+	case keyboard.KeyEsc:
+		if m.mode == modeFilter {
+			m.filter = ""
+			m.clamp()
+		}
+		m.mode = modeList
+		m.input = nil
+	case keyboard.KeyEnter:
+		task := strings.TrimSpace(string(m.input))
+		add := m.mode == modeAdd
+		m.mode = modeList
+		m.input = nil
+		if add && task != "" {
+			return action{op: opAdd, task: task}
+		}
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	default:
+		if k < keyboard.KeySpace {
+			return action{}
+		}
+		m.input = append(m.input, rune(k))
+	}
+
+	// filter the list while typing
+	if m.mode == modeFilter {
+		m.filter = string(m.input)
+		m.cursor = 0
+	}
+
+	return action{}
+}
+
+// lines renders the visible items, scrolling so the cursor stays
+// within the given number of rows
+func (m *model) lines(rows int) []line {
+	v := m.visible()
+	if rows < 1 {
+		rows = 1
+	}
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	if m.offset > len(v) {
+		m.offset = 0
+	}
+
+	lines := []line{}
+	for k := m.offset; k < len(v) && k < m.offset+rows; k++ {
+		done := "-"
+		if v[k].Done {
+			done = "X"
+		}
+		lines = append(lines, line{
+			text:     fmt.Sprintf("%s  %3d  %s", done, v[k].id, v[k].Task),
+			selected: k == m.cursor,
+		})
+	}
+
+	return lines
+}
+
+// prompt returns the help or input line shown under the list
+func (m *model) prompt() string {
+	switch m.mode {
+	case modeAdd:
+		return fmt.Sprintf("Add task: %s_", string(m.input))
+	case modeFilter:
+		return fmt.Sprintf("Filter: %s_", string(m.input))
+	}
+
+	help := "(a)dd  (c)omplete  (d)elete  (/)filter  (r)efresh  (q)uit"
+	if m.filter != "" {
+		help = fmt.Sprintf("Filter: %q (esc to clear)  %s", m.filter, help)
+	}
+	return help
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+func newTestModel() *model {
+	m := &model{}
+	m.setItems([]Item{
+		{Task: "Buy milk"},
+		{Task: "Write report", Done: true},
+		{Task: "Buy bread"},
+	})
+	return m
+}
+
+func typeKeys(m *model, s string) {
+	for _, r := range s {
+		m.key(keyboard.Key(r))
+	}
+}
+
+func TestKeyActions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		keys      []keyboard.Key
+		expAction action
+		expCursor int
+	}{
+		{name: "CompleteFirst",
+			keys:      []keyboard.Key{'c'},
+			expAction: action{op: opComplete, id: 1, task: "Buy milk"}},
+		{name: "DeleteSecond",
+			keys:      []keyboard.Key{keyboard.KeyArrowDown, 'd'},
+			expAction: action{op: opDelete, id: 2, task: "Write report"},
+			expCursor: 1},
+		{name: "CursorStopsAtEnd",
+			keys:      []keyboard.Key{'j', 'j', 'j', 'j'},
+			expCursor: 2},
+		{name: "CursorStopsAtStart",
+			keys:      []keyboard.Key{'j', 'k', 'k'},
+			expCursor: 0},
+		{name: "Refresh",
+			keys:      []keyboard.Key{'r'},
+			expAction: action{op: opRefresh}},
+		{name: "Quit",
+			keys:      []keyboard.Key{'q'},
+			expAction: action{op: opQuit}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestModel()
+
+			var act action
+			for _, k := range tc.keys {
+				act = m.key(k)
+			}
+
+			if act != tc.expAction {
+				t.Errorf("Expected action %+v, got %+v", tc.expAction, act)
+			}
+			if m.cursor != tc.expCursor {
+				t.Errorf("Expected cursor %d, got %d", tc.expCursor, m.cursor)
+			}
+		})
+	}
+}
+
+func TestAddTask(t *testing.T) {
+	m := newTestModel()
+
+	m.key('a')
+	typeKeys(m, "Call Bobx")
+	m.key(keyboard.KeyBackspace2)
+
+	expPrompt := "Add task: Call Bob_"
+	if m.prompt() != expPrompt {
+		t.Errorf("Expected prompt %q, got %q", expPrompt, m.prompt())
+	}
+
+	// keys used as commands in the list are plain text while typing
+	if m.key('q').op != opNone {
+		t.Errorf("Expected no action while typing")
+	}
+	m.key(keyboard.KeyBackspace2)
+
+	act := m.key(keyboard.KeyEnter)
+	exp := action{op: opAdd, task: "Call Bob"}
+	if act != exp {
+		t.Errorf("Expected action %+v, got %+v", exp, act)
+	}
+	if m.mode != modeList {
+		t.Errorf("Expected list mode after adding")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	m := newTestModel()
+
+	m.key('/')
+	typeKeys(m, "BUY")
+	m.key(keyboard.KeyEnter)
+
+	v := m.visible()
+	if len(v) != 2 {
+		t.Fatalf("Expected 2 visible items, got %d", len(v))
+	}
+
+	// IDs still refer to the position in the full list
+	m.key('j')
+	act := m.key('c')
+	exp := action{op: opComplete, id: 3, task: "Buy bread"}
+	if act != exp {
+		t.Errorf("Expected action %+v, got %+v", exp, act)
+	}
+
+	m.key(keyboard.KeyEsc)
+	if len(m.visible()) != 3 {
+		t.Errorf("Expected filter to be cleared")
+	}
+}
+
+func TestLines(t *testing.T) {
+	m := newTestModel()
+	m.key('j')
+	m.key('j')
+
+	lines := m.lines(2)
+	exp := []line{
+		{text: "X    2  Write report"},
+		{text: "-    3  Buy bread", selected: true},
+	}
+
+	if len(lines) != len(exp) {
+		t.Fatalf("Expected %d lines, got %d", len(exp), len(lines))
+	}
+	for k := range exp {
+		if lines[k] != exp[k] {
+			t.Errorf("Expected line %+v, got %+v", exp[k], lines[k])
+		}
+	}
+}
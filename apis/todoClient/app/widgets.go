@@ -0,0 +1,66 @@
+package app
+
+import (
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+type widgets struct {
+	txtList   *text.Text
+	txtPrompt *text.Text
+	txtStatus *text.Text
+}
+
+func newWidgets() (*widgets, error) {
+	w := &widgets{}
+	var err error
+
+	w.txtList, err = text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	w.txtPrompt, err = text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	w.txtStatus, err = text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// update replaces the content of every widget
+func (w *widgets) update(lines []line, prompt, status string) error {
+	w.txtList.Reset()
+	if len(lines) == 0 {
+		if err := w.txtList.Write("No items"); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range lines {
+		opts := []text.WriteOption{}
+		if l.selected {
+			opts = append(opts, text.WriteCellOpts(cell.Inverse()))
+		}
+		if err := w.txtList.Write(l.text, opts...); err != nil {
+			return err
+		}
+		if err := w.txtList.Write("\n"); err != nil {
+			return err
+		}
+	}
+
+	if err := w.txtPrompt.Write(prompt, text.WriteReplace()); err != nil {
+		return err
+	}
+
+	if status == "" {
+		status = " "
+	}
+	return w.txtStatus.Write(status, text.WriteReplace())
+}
@@ -5,11 +5,19 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 func TestListAction(t *testing.T) {
@@ -52,7 +60,7 @@ func TestListAction(t *testing.T) {
 
 			var out bytes.Buffer
 
-			err := listAction(&out, url)
+			err := listAction(&out, tableOutput, url, "")
 
 			if tc.expError != nil {
 				if err == nil {
@@ -117,7 +125,7 @@ Completed:    No
 
 			var out bytes.Buffer
 
-			err := viewAction(&out, url, tc.id)
+			err := viewAction(&out, tableOutput, url, "", tc.id)
 
 			if tc.expError != nil {
 				if err == nil {
@@ -183,7 +191,7 @@ func TestAddAction(t *testing.T) {
 	// Execute Add test
 	var out bytes.Buffer
 
-	if err := addAction(&out, url, args); err != nil {
+	if err := addAction(&out, tableOutput, url, "", args); err != nil {
 		t.Fatalf("Expected no error, got %q.", err)
 	}
 
@@ -220,7 +228,7 @@ func TestCompleteAction(t *testing.T) {
 	// Execute complete test
 	var out bytes.Buffer
 
-	if err := completeAction(&out, url, arg); err != nil {
+	if err := completeAction(&out, tableOutput, url, "", arg); err != nil {
 		t.Fatalf("Expected no error, got %q", err)
 	}
 
@@ -254,11 +262,561 @@ func TestDelAction(t *testing.T) {
 	// Execute Del test
 	var out bytes.Buffer
 
-	if err := delAction(&out, url, arg); err != nil {
+	if err := delAction(&out, tableOutput, url, "", arg); err != nil {
+		t.Fatalf("Expected no error, got %q", err)
+	}
+
+	if expOut != out.String() {
+		t.Errorf("Expected output %q, got %q", expOut, out.String())
+	}
+}
+
+func TestOfflineSync(t *testing.T) {
+	api := &fakeAPI{}
+	api.add("Task 1", "Task 2", "Task 3")
+
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+
+	url, cleanup := mockServer(api.ServeHTTP)
+
+	// Fetch the list once to populate the cache
+	var out bytes.Buffer
+	if err := listAction(&out, tableOutput, url, cacheFile); err != nil {
 		t.Fatalf("Expected no error, got %q", err)
 	}
+	cleanup()
+
+	// Queue changes while the server is down
+	offline := []struct {
+		name   string
+		action func(io.Writer) error
+		expOut string
+	}{
+		{name: "Add",
+			action: func(w io.Writer) error {
+				return addAction(w, tableOutput, url, cacheFile, []string{"Task", "4"})
+			},
+			expOut: "Server unreachable. Task \"Task 4\" queued to be added on next sync.\n"},
+		{name: "CompleteAdded",
+			action: func(w io.Writer) error { return completeAction(w, tableOutput, url, cacheFile, "4") },
+			expOut: "Server unreachable. Item number 4 queued to be marked as completed on next sync.\n"},
+		{name: "Complete",
+			action: func(w io.Writer) error { return completeAction(w, tableOutput, url, cacheFile, "1") },
+			expOut: "Server unreachable. Item number 1 queued to be marked as completed on next sync.\n"},
+		{name: "Delete",
+			action: func(w io.Writer) error { return delAction(w, tableOutput, url, cacheFile, "2") },
+			expOut: "Server unreachable. Item number 2 queued to be deleted on next sync.\n"},
+	}
+
+	for _, tc := range offline {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tc.action(&out); err != nil {
+				t.Fatalf("Expected no error, got %q", err)
+			}
+			if tc.expOut != out.String() {
+				t.Errorf("Expected output %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
 
+	t.Run("ListCached", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := listAction(&out, tableOutput, url, cacheFile); err != nil {
+			t.Fatalf("Expected no error, got %q", err)
+		}
+
+		expList := "X  1  Task 1\n-  2  Task 3\nX  3  Task 4\n"
+		if !strings.HasPrefix(out.String(), "Server unreachable.") {
+			t.Errorf("Expected offline notice, got %q", out.String())
+		}
+		if !strings.HasSuffix(out.String(), expList) {
+			t.Errorf("Expected list %q, got %q", expList, out.String())
+		}
+	})
+
+	// Task 1 is removed on the server before the sync happens, which
+	// also shifts the position of Task 2
+	api.items = api.items[1:]
+
+	url, cleanup = mockServer(api.ServeHTTP)
+	defer cleanup()
+
+	out.Reset()
+	err := syncAction(&out, url, cacheFile)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected error %q, got %q", ErrConflict, err)
+	}
+
+	expOut := "Synced 3 change(s).\n1 conflict(s):\n" +
+		"  complete 1: task \"Task 1\" no longer exists on the server\n"
 	if expOut != out.String() {
 		t.Errorf("Expected output %q, got %q", expOut, out.String())
 	}
+
+	if len(api.items) != 2 {
+		t.Fatalf("Expected 2 items on the server, got %d", len(api.items))
+	}
+	if api.items[0].Task != "Task 3" || api.items[0].Done {
+		t.Errorf("Expected open item %q, got %+v", "Task 3", api.items[0])
+	}
+	if api.items[1].Task != "Task 4" || !api.items[1].Done {
+		t.Errorf("Expected completed item %q, got %+v", "Task 4", api.items[1])
+	}
+
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Pending) != 0 {
+		t.Errorf("Expected no pending changes, got %d", len(c.Pending))
+	}
+	if len(c.Items) != 2 {
+		t.Errorf("Expected 2 cached items, got %d", len(c.Items))
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	api := &fakeAPI{}
+	api.add("Task 1")
+
+	// the server rejects one task and fails to list the tasks right
+	// after adding the other one
+	var mu sync.Mutex
+	failList := false
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost:
+			var body struct {
+				Task string `json:"task"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Task == "Rejected" {
+				http.Error(w, "400 - invalid task", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(strings.NewReader(fmt.Sprintf(`{"task": %q}`, body.Task)))
+			failList = true
+		case r.Method == http.MethodGet && failList:
+			failList = false
+			http.Error(w, "500 - internal error", http.StatusInternalServerError)
+			return
+		}
+		api.ServeHTTP(w, r)
+	}
+
+	url, cleanup := mockServer(handler)
+	defer cleanup()
+
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	err := queueChange(cacheFile, func(c *cache) error {
+		c.refresh(api.items)
+		c.add("Rejected")
+		c.add("Added")
+		return c.complete(1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = syncAction(&out, url, cacheFile)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected error %q, got %q", ErrConflict, err)
+	}
+
+	expOut := "Synced 2 change(s).\n1 conflict(s):\n  add 2: "
+	if !strings.HasPrefix(out.String(), expOut) {
+		t.Errorf("Expected output starting with %q, got %q", expOut, out.String())
+	}
+
+	// the rejected task doesn't block the others, and the added one
+	// isn't sent twice
+	if len(api.items) != 2 || !api.items[0].Done || api.items[1].Task != "Added" {
+		t.Errorf("Expected completed %q and %q on the server, got %+v",
+			"Task 1", "Added", api.items)
+	}
+
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Pending) != 0 {
+		t.Errorf("Expected no pending changes, got %+v", c.Pending)
+	}
+
+	// nothing is left to send on the next sync
+	out.Reset()
+	if err := syncAction(&out, url, cacheFile); err != nil {
+		t.Fatalf("Expected no error, got %q", err)
+	}
+	if len(api.items) != 2 {
+		t.Errorf("Expected 2 items on the server, got %+v", api.items)
+	}
+}
+
+func TestSyncOffline(t *testing.T) {
+	api := &fakeAPI{}
+	url, cleanup := mockServer(api.ServeHTTP)
+	// the server is down during the sync
+	cleanup()
+
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	err := queueChange(cacheFile, func(c *cache) error {
+		c.add("Task 1")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := syncAction(&out, url, cacheFile); !errors.Is(err, ErrConnection) {
+		t.Fatalf("Expected error %q, got %q", ErrConnection, err)
+	}
+
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Pending) != 1 {
+		t.Errorf("Expected the change kept for the next sync, got %+v", c.Pending)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+		golden string
+	}{
+		{name: "Table", output: "table", golden: "table"},
+		{name: "JSON", output: "json", golden: "json"},
+		{name: "YAML", output: "yaml", golden: "yaml"},
+		{name: "CSV", output: "csv", golden: "csv"},
+		{name: "Template",
+			output: "template={{.ID}}: {{.Task}} ({{if .Done}}done{{else}}open{{end}})",
+			golden: "template"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newPrinter(tc.output)
+			if err != nil {
+				t.Fatalf("Expected no error, got %q", err)
+			}
+
+			t.Run("List", func(t *testing.T) {
+				url, cleanup := mockServer(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(testResp["resultsMany"].Status)
+						fmt.Fprintln(w, testResp["resultsMany"].Body)
+					})
+				defer cleanup()
+
+				var out bytes.Buffer
+				if err := listAction(&out, p, url, ""); err != nil {
+					t.Fatalf("Expected no error, got %q", err)
+				}
+				assertGolden(t, "list."+tc.golden, out.Bytes())
+			})
+
+			t.Run("View", func(t *testing.T) {
+				url, cleanup := mockServer(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(testResp["resultsOne"].Status)
+						fmt.Fprintln(w, testResp["resultsOne"].Body)
+					})
+				defer cleanup()
+
+				var out bytes.Buffer
+				if err := viewAction(&out, p, url, "", "1"); err != nil {
+					t.Fatalf("Expected no error, got %q", err)
+				}
+				assertGolden(t, "view."+tc.golden, out.Bytes())
+			})
+		})
+	}
+}
+
+func TestNewPrinterInvalid(t *testing.T) {
+	for _, output := range []string{"xml", "json=x", "template=", "template={{.ID"} {
+		t.Run(output, func(t *testing.T) {
+			if _, err := newPrinter(output); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected error %q, got %q", ErrInvalid, err)
+			}
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name string, result []byte) {
+	t.Helper()
+
+	goldenFile := filepath.Join("testdata", name)
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, result) {
+		t.Logf("golden:\n%s\n", expected)
+		t.Logf("result:\n%s\n", result)
+		t.Errorf("Result content does not match golden file %s", goldenFile)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		action      func(url string) error
+		failures    int
+		failStatus  int
+		expAttempts int
+		expError    error
+	}{
+		{name: "ListRetried",
+			action:      func(url string) error { return listAction(io.Discard, tableOutput, url, "") },
+			failures:    2,
+			failStatus:  http.StatusServiceUnavailable,
+			expAttempts: 3},
+		{name: "AddRetried",
+			action: func(url string) error {
+				return addAction(io.Discard, tableOutput, url, "", []string{"Task 1"})
+			},
+			failures:    1,
+			failStatus:  http.StatusBadGateway,
+			expAttempts: 2},
+		{name: "DeleteNotRetried",
+			action:      func(url string) error { return delAction(io.Discard, tableOutput, url, "", "1") },
+			failures:    1,
+			failStatus:  http.StatusBadGateway,
+			expAttempts: 1,
+			expError:    ErrInvalidResponse},
+		{name: "DeleteRetriedWhenThrottled",
+			action:      func(url string) error { return delAction(io.Discard, tableOutput, url, "", "1") },
+			failures:    1,
+			failStatus:  http.StatusTooManyRequests,
+			expAttempts: 2},
+		{name: "GiveUp",
+			action:      func(url string) error { return completeAction(io.Discard, tableOutput, url, "", "1") },
+			failures:    10,
+			failStatus:  http.StatusServiceUnavailable,
+			expAttempts: 4,
+			expError:    ErrInvalidResponse},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			keys := map[string]bool{}
+
+			url, cleanup := mockServer(
+				func(w http.ResponseWriter, r *http.Request) {
+					attempts++
+					if r.Method == http.MethodPost {
+						keys[r.Header.Get("Idempotency-Key")] = true
+					}
+
+					if attempts <= tc.failures {
+						w.Header().Set("Retry-After", "0")
+						w.WriteHeader(tc.failStatus)
+						return
+					}
+
+					resp := testResp["noContent"]
+					switch r.Method {
+					case http.MethodGet:
+						resp = testResp["resultsMany"]
+					case http.MethodPost:
+						resp = testResp["created"]
+					}
+					w.WriteHeader(resp.Status)
+					fmt.Fprintln(w, resp.Body)
+				})
+			defer cleanup()
+
+			err := tc.action(url)
+
+			if tc.expError != nil {
+				if !errors.Is(err, tc.expError) {
+					t.Errorf("Expected error %q, got %q", tc.expError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error, got %q", err)
+			}
+
+			if attempts != tc.expAttempts {
+				t.Errorf("Expected %d attempts, got %d", tc.expAttempts, attempts)
+			}
+
+			if len(keys) > 1 || keys[""] {
+				t.Errorf("Expected one idempotency key for all attempts, got %v", keys)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	defer func(cfg clientConfig) { clientCfg = cfg }(clientCfg)
+	clientCfg.Timeout = 10 * time.Millisecond
+	clientCfg.Retries = 0
+
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		})
+	defer cleanup()
+
+	err := listAction(io.Discard, tableOutput, url, "")
+	if !errors.Is(err, ErrConnection) {
+		t.Errorf("Expected error %q, got %q", ErrConnection, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name    string
+		header  string
+		expWait time.Duration
+		expOK   bool
+	}{
+		{name: "Empty", header: ""},
+		{name: "Seconds", header: "3", expWait: 3 * time.Second, expOK: true},
+		{name: "Capped", header: "3600", expWait: time.Minute, expOK: true},
+		{name: "PastDate", header: "Mon, 02 Jan 2006 15:04:05 GMT", expWait: 0, expOK: true},
+		{name: "Invalid", header: "soon"},
+	}
+
+	defer func(cfg clientConfig) { clientCfg = cfg }(clientCfg)
+	clientCfg.MaxRetryWait = time.Minute
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wait, ok := retryAfter(tc.header)
+			if ok != tc.expOK {
+				t.Fatalf("Expected ok %t, got %t", tc.expOK, ok)
+			}
+			if wait != tc.expWait {
+				t.Errorf("Expected wait %s, got %s", tc.expWait, wait)
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	defer func(cfg clientConfig) { clientCfg = cfg }(clientCfg)
+
+	api := &fakeAPI{}
+	api.add("Task 1", "Task 2", "Task 3", "Task 10")
+	api.items[1].Done = true
+
+	url, cleanup := mockServer(api.ServeHTTP)
+	defer cleanup()
+
+	testCases := []struct {
+		name       string
+		command    string
+		toComplete string
+		expOut     string
+	}{
+		{name: "CompleteOpenOnly", command: "complete",
+			expOut: "1\tTask 1\n3\tTask 3\n4\tTask 10\n:4\n"},
+		{name: "ViewAll", command: "view",
+			expOut: "1\tTask 1\n2\tTask 2\n3\tTask 3\n4\tTask 10\n:4\n"},
+		{name: "Prefix", command: "del", toComplete: "2",
+			expOut: "2\tTask 2\n:4\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(io.Discard)
+			rootCmd.SetArgs([]string{"__complete", tc.command,
+				"--api-root", url, "--cache-file", "", tc.toComplete})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Expected no error, got %q", err)
+			}
+
+			if tc.expOut != out.String() {
+				t.Errorf("Expected output %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+}
+
+func TestCompletionAction(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := completionAction(&out, shell); err != nil {
+				t.Fatalf("Expected no error, got %q", err)
+			}
+			if !strings.Contains(out.String(), "todoClient") {
+				t.Errorf("Expected %s completion script for todoClient", shell)
+			}
+		})
+	}
+
+	if err := completionAction(io.Discard, "tcsh"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected error %q, got %q", ErrInvalid, err)
+	}
+}
+
+func TestCacheFilePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	defer viper.Set("cache-file", "")
+
+	testCases := []struct {
+		name    string
+		flag    string
+		expFile string
+		noHome  bool
+		expErr  error
+	}{
+		{name: "Disabled", flag: "", expFile: ""},
+		{name: "Path", flag: "cache.json", expFile: "cache.json"},
+		{name: "Home", flag: "~/.todoClient.cache.json",
+			expFile: filepath.Join(home, ".todoClient.cache.json")},
+		{name: "NoHome", flag: "~/.todoClient.cache.json", noHome: true, expErr: ErrInvalid},
+		{name: "NoHomeNotNeeded", flag: "cache.json", noHome: true, expFile: "cache.json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.noHome {
+				t.Setenv("HOME", "")
+			}
+			viper.Set("cache-file", tc.flag)
+
+			f, err := cacheFilePath()
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expErr, err)
+			}
+			if f != tc.expFile {
+				t.Errorf("Expected cache file %q, got %q", tc.expFile, f)
+			}
+		})
+	}
+}
+
+func TestTUIInvalidRefresh(t *testing.T) {
+	defer tuiCmd.Flags().Set("refresh", "30s")
+
+	for _, refresh := range []string{"0", "-1s"} {
+		t.Run(refresh, func(t *testing.T) {
+			rootCmd.SetOut(io.Discard)
+			rootCmd.SetErr(io.Discard)
+			rootCmd.SetArgs([]string{"tui", "--refresh", refresh})
+
+			if err := rootCmd.Execute(); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected error %q, got %q", ErrInvalid, err)
+			}
+		})
+	}
 }
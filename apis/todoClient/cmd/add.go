@@ -21,17 +21,44 @@ var addCmd = &cobra.Command{
 	Args:         cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
-		return addAction(os.Stdout, apiRoot, args)
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
+
+		return addAction(os.Stdout, p, apiRoot, cacheFile, args)
 	},
 }
 
-func addAction(out io.Writer, apiRoot string, args []string) error {
+func addAction(out io.Writer, p *printer, apiRoot, cacheFile string, args []string) error {
 	task := strings.Join(args, " ")
 
 	if err := addItem(apiRoot, task); err != nil {
-		return err
+		if !isOffline(err, cacheFile) {
+			return err
+		}
+
+		err := queueChange(cacheFile, func(c *cache) error {
+			c.add(task)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !p.isTable() {
+			return p.change(out, changeView{Action: opAdd, Task: task, Queued: true})
+		}
+		return printAddQueued(out, task)
 	}
 
+	if !p.isTable() {
+		return p.change(out, changeView{Action: opAdd, Task: task})
+	}
 	return printAdd(out, task)
 }
 
@@ -40,6 +67,12 @@ func printAdd(out io.Writer, task string) error {
 	return err
 }
 
+func printAddQueued(out io.Writer, task string) error {
+	_, err := fmt.Fprintf(out,
+		"Server unreachable. Task %q queued to be added on next sync.\n", task)
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
 
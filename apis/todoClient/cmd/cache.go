@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	opAdd      = "add"
+	opComplete = "complete"
+	opDelete   = "del"
+)
+
+// pendingOp is a change made while the API was unreachable. Item holds a
+// snapshot of the target item at the time the change was queued. IDs are
+// positions in the list, so sync uses the snapshot to find the same item
+// on the server.
+type pendingOp struct {
+	Op       string
+	ID       int
+	Item     item
+	QueuedAt time.Time
+}
+
+// cache holds the last list fetched from the API and the changes
+// waiting to be sent with the sync command
+type cache struct {
+	Items     []item
+	Pending   []pendingOp
+	FetchedAt time.Time
+}
+
+// itemKey identifies an item regardless of its position in the list
+type itemKey struct {
+	Task      string
+	CreatedAt int64
+}
+
+func keyOf(i item) itemKey {
+	return itemKey{Task: i.Task, CreatedAt: i.CreatedAt.UnixNano()}
+}
+
+// loadCache reads the cache file. A missing or empty file returns an
+// empty cache
+func loadCache(filename string) (*cache, error) {
+	c := &cache{}
+
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if len(file) == 0 {
+		return c, nil
+	}
+
+	if err := json.Unmarshal(file, c); err != nil {
+		return nil, fmt.Errorf("Cannot read cache %s: %w", filename, err)
+	}
+
+	return c, nil
+}
+
+// save encodes the cache as JSON and writes it to filename
+func (c *cache) save(filename string) error {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, js, 0644)
+}
+
+// refresh replaces the cached items with the list fetched from the API.
+// Pending changes are kept until they are synced
+func (c *cache) refresh(items []item) {
+	c.Items = items
+	c.FetchedAt = time.Now()
+}
+
+// add appends a new task to the cached list and queues it
+func (c *cache) add(task string) {
+	i := item{
+		Task:      task,
+		CreatedAt: time.Now(),
+	}
+	c.Items = append(c.Items, i)
+	c.queue(opAdd, len(c.Items), i)
+}
+
+// complete marks the cached item id as done and queues the change
+func (c *cache) complete(id int) error {
+	if id <= 0 || id > len(c.Items) {
+		return fmt.Errorf("%w: Item %d not in cache", ErrNotFound, id)
+	}
+
+	c.queue(opComplete, id, c.Items[id-1])
+	c.Items[id-1].Done = true
+	c.Items[id-1].CompletedAt = time.Now()
+
+	return nil
+}
+
+// delete removes the cached item id and queues the change
+func (c *cache) delete(id int) error {
+	if id <= 0 || id > len(c.Items) {
+		return fmt.Errorf("%w: Item %d not in cache", ErrNotFound, id)
+	}
+
+	c.queue(opDelete, id, c.Items[id-1])
+	c.Items = append(c.Items[:id-1], c.Items[id:]...)
+
+	return nil
+}
+
+func (c *cache) queue(op string, id int, i item) {
+	c.Pending = append(c.Pending, pendingOp{
+		Op:       op,
+		ID:       id,
+		Item:     i,
+		QueuedAt: time.Now(),
+	})
+}
+
+// cacheFilePath returns the cache file set with --cache-file. A leading
+// ~ stands for the home directory, which is only looked up then
+func cacheFilePath() (string, error) {
+	f := viper.GetString("cache-file")
+	if f != "~" && !strings.HasPrefix(f, "~/") {
+		return f, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: cache file %s: %s", ErrInvalid, f, err)
+	}
+
+	return filepath.Join(home, f[1:]), nil
+}
+
+// isOffline reports whether err means the API is unreachable and a
+// cache file is configured to fall back on
+func isOffline(err error, cacheFile string) bool {
+	return cacheFile != "" && errors.Is(err, ErrConnection)
+}
+
+// updateCache stores items as the latest list fetched from the API
+func updateCache(cacheFile string, items []item) error {
+	if cacheFile == "" {
+		return nil
+	}
+
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		return err
+	}
+
+	c.refresh(items)
+	return c.save(cacheFile)
+}
+
+// queueChange loads the cache, applies fn to it and saves the result
+func queueChange(cacheFile string, fn func(c *cache) error) error {
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(c); err != nil {
+		return err
+	}
+
+	return c.save(cacheFile)
+}
+
+func printOffline(out io.Writer, c *cache) error {
+	if c.FetchedAt.IsZero() {
+		_, err := fmt.Fprintln(out, "Server unreachable. No cached list available.")
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "Server unreachable. Showing cached list from %s.\n",
+		c.FetchedAt.Format(timeFormat))
+	return err
+}
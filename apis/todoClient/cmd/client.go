@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -18,6 +24,7 @@ var (
 	ErrInvalidResponse = errors.New("Invalid server response")
 	ErrInvalid         = errors.New("Invalid data")
 	ErrNotNumber       = errors.New("Not a number")
+	ErrConflict        = errors.New("Sync conflict")
 )
 
 type item struct {
@@ -33,17 +40,153 @@ type response struct {
 	TotalResults int    `json:"total_results"`
 }
 
+// clientConfig controls how requests are sent to the API
+type clientConfig struct {
+	Timeout      time.Duration
+	Retries      int
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+}
+
+// clientCfg is set from the root command flags before any command runs
+var clientCfg = clientConfig{
+	Timeout:      10 * time.Second,
+	Retries:      3,
+	RetryWait:    500 * time.Millisecond,
+	MaxRetryWait: 30 * time.Second,
+}
+
+// jitter randomizes the wait between retries so clients don't retry in
+// lockstep
+var (
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMu sync.Mutex
+)
+
 func newClient() *http.Client {
 	c := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: clientCfg.Timeout,
 	}
 	return c
 }
 
+// do sends req, retrying with exponential backoff when the connection
+// fails or the API asks to try again later. Requests that are not
+// idempotent are only retried when they never reached the API
+func do(req *http.Request, idempotent bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		r, err := newClient().Do(req)
+		last := attempt >= clientCfg.Retries
+
+		if err != nil {
+			if last || !(idempotent || isDialError(err)) {
+				return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+			}
+			time.Sleep(backoff(attempt))
+			continue
+		}
+
+		if last || !retryStatus(r.StatusCode, idempotent) {
+			return r, nil
+		}
+
+		wait, ok := retryAfter(r.Header.Get("Retry-After"))
+		if !ok {
+			wait = backoff(attempt)
+		}
+		io.Copy(io.Discard, r.Body)
+		r.Body.Close()
+		time.Sleep(wait)
+	}
+}
+
+// isDialError reports whether err happened while connecting, before
+// any part of the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryStatus reports whether a response with the given status code is
+// worth retrying. Gateway errors may hide a request that was processed,
+// so only idempotent requests retry them
+func retryStatus(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// backoff returns a random wait between zero and an exponentially
+// growing limit, capped by MaxRetryWait
+func backoff(attempt int) time.Duration {
+	limit := clientCfg.RetryWait << attempt
+	if limit <= 0 || limit > clientCfg.MaxRetryWait {
+		limit = clientCfg.MaxRetryWait
+	}
+	if limit <= 0 {
+		return 0
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitter.Int63n(int64(limit) + 1))
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date. The wait is capped by MaxRetryWait
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		wait = time.Until(t)
+	} else {
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > clientCfg.MaxRetryWait {
+		wait = clientCfg.MaxRetryWait
+	}
+	return wait, true
+}
+
+// newIdempotencyKey returns a random key that lets the API recognize a
+// retried POST request
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func getItems(url string) ([]item, error) {
-	r, err := newClient().Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := do(req, true)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+		return nil, err
 	}
 	defer r.Body.Close()
 
@@ -104,7 +247,18 @@ func sendRequest(url, method, contentType string, expStatus int, body io.Reader)
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	r, err := newClient().Do(req)
+	// IDs are positions in the list, so repeating a delete could remove
+	// a different item. A POST is safe to repeat once it carries a key
+	idempotent := method != http.MethodDelete
+	if method == http.MethodPost {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	r, err := do(req, idempotent)
 	if err != nil {
 		return err
 	}
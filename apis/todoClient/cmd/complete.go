@@ -21,21 +21,46 @@ var completeCmd = &cobra.Command{
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
 
-		return completeAction(os.Stdout, apiRoot, args[0])
+		p, err := newPrinter(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
+
+		return completeAction(os.Stdout, p, apiRoot, cacheFile, args[0])
 	},
 }
 
-func completeAction(out io.Writer, apiRoot, arg string) error {
+func completeAction(out io.Writer, p *printer, apiRoot, cacheFile, arg string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
 	}
 
 	if err := completeItem(apiRoot, id); err != nil {
-		return err
+		if !isOffline(err, cacheFile) {
+			return err
+		}
+
+		err := queueChange(cacheFile, func(c *cache) error {
+			return c.complete(id)
+		})
+		if err != nil {
+			return err
+		}
+		if !p.isTable() {
+			return p.change(out, changeView{Action: opComplete, ID: id, Queued: true})
+		}
+		return printCompleteQueued(out, id)
 	}
 
+	if !p.isTable() {
+		return p.change(out, changeView{Action: opComplete, ID: id})
+	}
 	return printComplete(out, id)
 }
 
@@ -44,6 +69,12 @@ func printComplete(out io.Writer, id int) error {
 	return err
 }
 
+func printCompleteQueued(out io.Writer, id int) error {
+	_, err := fmt.Fprintf(out,
+		"Server unreachable. Item number %d queued to be marked as completed on next sync.\n", id)
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(completeCmd)
 
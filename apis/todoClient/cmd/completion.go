@@ -0,0 +1,117 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate shell completion for todoClient",
+	Long: `To load your completions run
+	source <(todoClient completion bash)
+
+	for zsh or fish, use
+	source <(todoClient completion zsh)
+	todoClient completion fish | source
+
+	to load completions automatically on login, add the line to your
+	shell startup file, such as ~/.bashrc.
+
+	Item IDs are completed with their task, fetched from the API or
+	the local cache when the API is unreachable.
+	`,
+	SilenceUsage:      true,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgs:         []string{"bash", "zsh", "fish"},
+	ValidArgsFunction: cobra.FixedCompletions([]string{"bash", "zsh", "fish"}, cobra.ShellCompDirectiveNoFileComp),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := "bash"
+		if len(args) > 0 {
+			shell = args[0]
+		}
+
+		return completionAction(os.Stdout, shell)
+	},
+}
+
+func completionAction(out io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(out, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(out)
+	case "fish":
+		return rootCmd.GenFishCompletion(out, true)
+	}
+
+	return fmt.Errorf("%w: Unsupported shell %q", ErrInvalid, shell)
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+
+	completeCmd.ValidArgsFunction = completeItems(true)
+	deleteCmd.ValidArgsFunction = completeItems(false)
+	viewCmd.ValidArgsFunction = completeItems(false)
+}
+
+// completeItems returns a function that suggests item IDs, described by
+// their task, for commands taking an ID. With open set, completed items
+// are left out
+func completeItems(open bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// don't keep the shell waiting on retries
+		setClientConfig()
+		clientCfg.Retries = 0
+
+		items, err := getAllOrEmpty(viper.GetString("api-root"))
+		if err != nil {
+			cacheFile, cerr := cacheFilePath()
+			if cerr != nil || !isOffline(err, cacheFile) {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			c, err := loadCache(cacheFile)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			items = c.Items
+		}
+
+		return itemCompletions(items, open, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func itemCompletions(items []item, open bool, toComplete string) []string {
+	completions := []string{}
+
+	for k, i := range items {
+		if open && i.Done {
+			continue
+		}
+
+		id := strconv.Itoa(k + 1)
+		if !strings.HasPrefix(id, toComplete) {
+			continue
+		}
+
+		completions = append(completions, fmt.Sprintf("%s\t%s", id, i.Task))
+	}
+
+	return completions
+}
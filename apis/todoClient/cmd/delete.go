@@ -21,21 +21,46 @@ var deleteCmd = &cobra.Command{
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
 
-		return delAction(os.Stdout, apiRoot, args[0])
+		p, err := newPrinter(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
+
+		return delAction(os.Stdout, p, apiRoot, cacheFile, args[0])
 	},
 }
 
-func delAction(out io.Writer, apiRoot, arg string) error {
+func delAction(out io.Writer, p *printer, apiRoot, cacheFile, arg string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
 	}
 
 	if err := deleteItem(apiRoot, id); err != nil {
-		return err
+		if !isOffline(err, cacheFile) {
+			return err
+		}
+
+		err := queueChange(cacheFile, func(c *cache) error {
+			return c.delete(id)
+		})
+		if err != nil {
+			return err
+		}
+		if !p.isTable() {
+			return p.change(out, changeView{Action: opDelete, ID: id, Queued: true})
+		}
+		return printDelQueued(out, id)
 	}
 
+	if !p.isTable() {
+		return p.change(out, changeView{Action: opDelete, ID: id})
+	}
 	return printDel(out, id)
 }
 
@@ -44,6 +69,12 @@ func printDel(out io.Writer, id int) error {
 	return err
 }
 
+func printDelQueued(out io.Writer, id int) error {
+	_, err := fmt.Fprintf(out,
+		"Server unreachable. Item number %d queued to be deleted on next sync.\n", id)
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 
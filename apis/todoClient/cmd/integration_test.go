@@ -32,7 +32,7 @@ func TestIntegration(t *testing.T) {
 		// Execute Add test
 		var out bytes.Buffer
 
-		if err := addAction(&out, apiRoot, args); err != nil {
+		if err := addAction(&out, tableOutput, apiRoot, "", args); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -43,7 +43,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListTasks", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, tableOutput, apiRoot, ""); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -71,7 +71,7 @@ func TestIntegration(t *testing.T) {
 
 	vRes := t.Run("ViewTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := viewAction(&out, apiRoot, taskId); err != nil {
+		if err := viewAction(&out, tableOutput, apiRoot, "", taskId); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -96,7 +96,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("CompleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := completeAction(&out, apiRoot, taskId); err != nil {
+		if err := completeAction(&out, tableOutput, apiRoot, "", taskId); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -109,7 +109,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListCompletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, tableOutput, apiRoot, ""); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -135,7 +135,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("DeleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := delAction(&out, apiRoot, taskId); err != nil {
+		if err := delAction(&out, tableOutput, apiRoot, "", taskId); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
@@ -148,7 +148,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Run("ListDeletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot); err != nil {
+		if err := listAction(&out, tableOutput, apiRoot, ""); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 
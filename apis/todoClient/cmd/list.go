@@ -20,8 +20,17 @@ var listCmd = &cobra.Command{
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
 
-		return listAction(os.Stdout, apiRoot)
+		return listAction(os.Stdout, p, apiRoot, cacheFile)
 	},
 }
 
@@ -39,12 +48,31 @@ func init() {
 	// listCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func listAction(out io.Writer, apiRoot string) error {
+func listAction(out io.Writer, p *printer, apiRoot, cacheFile string) error {
 	items, err := getAll(apiRoot)
 	if err != nil {
+		if !isOffline(err, cacheFile) {
+			return err
+		}
+
+		// fall back to the last list fetched from the API
+		c, err := loadCache(cacheFile)
+		if err != nil {
+			return err
+		}
+		if p.isTable() {
+			if err := printOffline(out, c); err != nil {
+				return err
+			}
+		}
+		return p.items(out, c.Items)
+	}
+
+	if err := updateCache(cacheFile, items); err != nil {
 		return err
 	}
-	return printAll(out, items)
+
+	return p.items(out, items)
 }
 
 func printAll(out io.Writer, items []item) error {
@@ -1,10 +1,24 @@
 package cmd
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	// keep retries against closed test servers short
+	clientCfg.RetryWait = time.Millisecond
+	clientCfg.MaxRetryWait = 10 * time.Millisecond
+	os.Exit(m.Run())
+}
+
 // testResp simulates test reponses from the API
 var testResp = map[string]struct {
 	Status int
@@ -82,3 +96,57 @@ func mockServer(h http.HandlerFunc) (string, func()) {
 		ts.Close()
 	}
 }
+
+// fakeAPI is an in-memory version of the todo API used to test
+// operations that change the list on the server
+type fakeAPI struct {
+	mu    sync.Mutex
+	items []item
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/todo" {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(response{
+				Results:      f.items,
+				TotalResults: len(f.items),
+			})
+		case http.MethodPost:
+			var body struct {
+				Task string `json:"task"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			f.items = append(f.items, item{Task: body.Task, CreatedAt: time.Now()})
+			w.WriteHeader(http.StatusCreated)
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/todo/"))
+	if err != nil || id < 1 || id > len(f.items) {
+		http.Error(w, "404 - not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		f.items[id-1].Done = true
+		f.items[id-1].CompletedAt = time.Now()
+	case http.MethodDelete:
+		f.items = append(f.items[:id-1], f.items[id:]...)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeAPI) add(tasks ...string) {
+	for _, t := range tasks {
+		f.items = append(f.items, item{Task: t, CreatedAt: time.Now()})
+	}
+}
+
+// tableOutput prints results the same way as the default --output flag
+var tableOutput = &printer{format: formatTable}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatYAML     = "yaml"
+	formatCSV      = "csv"
+	formatTemplate = "template"
+)
+
+// itemView is the representation of an item in structured output
+type itemView struct {
+	ID          int        `json:"id" yaml:"id"`
+	Task        string     `json:"task" yaml:"task"`
+	Done        bool       `json:"done" yaml:"done"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" yaml:"completed_at,omitempty"`
+}
+
+func newItemView(id int, i item) itemView {
+	v := itemView{
+		ID:        id,
+		Task:      i.Task,
+		Done:      i.Done,
+		CreatedAt: i.CreatedAt,
+	}
+	if i.Done {
+		completed := i.CompletedAt
+		v.CompletedAt = &completed
+	}
+	return v
+}
+
+// changeView is the result of a command that modifies the list
+type changeView struct {
+	Action string `json:"action" yaml:"action"`
+	ID     int    `json:"id,omitempty" yaml:"id,omitempty"`
+	Task   string `json:"task,omitempty" yaml:"task,omitempty"`
+	Queued bool   `json:"queued" yaml:"queued"`
+}
+
+// printer writes command results in the format selected by --output
+type printer struct {
+	format string
+	tmpl   *template.Template
+}
+
+// newPrinter parses the value of the --output flag. Templates are given
+// as template=<Go template> and run once for every item
+func newPrinter(output string) (*printer, error) {
+	name, text, isTmpl := strings.Cut(output, "=")
+
+	switch name {
+	case formatTable, formatJSON, formatYAML, formatCSV:
+		if isTmpl {
+			return nil, fmt.Errorf("%w: Output format %q takes no value", ErrInvalid, name)
+		}
+		return &printer{format: name}, nil
+	case formatTemplate:
+		if text == "" {
+			return nil, fmt.Errorf("%w: Output template is empty", ErrInvalid)
+		}
+		t, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+		return &printer{format: name, tmpl: t}, nil
+	}
+
+	return nil, fmt.Errorf("%w: Unknown output format %q", ErrInvalid, output)
+}
+
+// isTable reports whether the output is meant to be read by people
+// rather than parsed, so extra notices can be printed along with it
+func (p *printer) isTable() bool {
+	return p.format == formatTable
+}
+
+func (p *printer) items(out io.Writer, items []item) error {
+	views := make([]itemView, len(items))
+	for k, v := range items {
+		views[k] = newItemView(k+1, v)
+	}
+
+	switch p.format {
+	case formatTable:
+		return printAll(out, items)
+	case formatJSON:
+		return printJSON(out, views)
+	case formatYAML:
+		return printYAML(out, views)
+	case formatCSV:
+		return printItemsCSV(out, views)
+	}
+
+	for _, v := range views {
+		if err := p.execute(out, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *printer) item(out io.Writer, id int, i item) error {
+	v := newItemView(id, i)
+
+	switch p.format {
+	case formatTable:
+		return printOne(out, i)
+	case formatJSON:
+		return printJSON(out, v)
+	case formatYAML:
+		return printYAML(out, v)
+	case formatCSV:
+		return printItemsCSV(out, []itemView{v})
+	}
+
+	return p.execute(out, v)
+}
+
+// change prints the result of add, complete or del. Table output is
+// produced by the commands themselves
+func (p *printer) change(out io.Writer, c changeView) error {
+	switch p.format {
+	case formatJSON:
+		return printJSON(out, c)
+	case formatYAML:
+		return printYAML(out, c)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		w.Write([]string{"action", "id", "task", "queued"})
+		w.Write([]string{c.Action, strconv.Itoa(c.ID), c.Task,
+			strconv.FormatBool(c.Queued)})
+		w.Flush()
+		return w.Error()
+	case formatTemplate:
+		return p.execute(out, c)
+	}
+
+	return fmt.Errorf("%w: No structured output for format %q", ErrInvalid, p.format)
+}
+
+func (p *printer) execute(out io.Writer, data any) error {
+	if err := p.tmpl.Execute(out, data); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printYAML(out io.Writer, v any) error {
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func printItemsCSV(out io.Writer, views []itemView) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"id", "task", "done", "created_at", "completed_at"})
+
+	for _, v := range views {
+		completed := ""
+		if v.CompletedAt != nil {
+			completed = v.CompletedAt.Format(time.RFC3339)
+		}
+		w.Write([]string{
+			strconv.Itoa(v.ID),
+			v.Task,
+			strconv.FormatBool(v.Done),
+			v.CreatedAt.Format(time.RFC3339),
+			completed,
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -21,6 +21,9 @@ var (
 var rootCmd = &cobra.Command{
 	Use:   "todoClient",
 	Short: "A Todo API client",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		setClientConfig()
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -49,7 +52,28 @@ func init() {
 	viper.SetEnvKeyReplacer(replacer)
 	viper.SetEnvPrefix("TODO")
 
+	rootCmd.PersistentFlags().String("cache-file", "",
+		"Local cache used when the API is unreachable, such as ~/.todoClient.cache.json (empty to disable)")
+
+	rootCmd.PersistentFlags().StringP("output", "o", formatTable,
+		"Output format: table, json, yaml, csv or template=<Go template>")
+
+	rootCmd.PersistentFlags().Duration("timeout", clientCfg.Timeout,
+		"Timeout for each request to the API")
+	rootCmd.PersistentFlags().Int("retries", clientCfg.Retries,
+		"Number of times a failed request is retried")
+	rootCmd.PersistentFlags().Duration("retry-wait", clientCfg.RetryWait,
+		"Base wait between retries, doubled on every attempt")
+	rootCmd.PersistentFlags().Duration("retry-max-wait", clientCfg.MaxRetryWait,
+		"Maximum wait between retries")
+
 	viper.BindPFlag("api-root", rootCmd.PersistentFlags().Lookup("api-root"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	viper.BindPFlag("retry-wait", rootCmd.PersistentFlags().Lookup("retry-wait"))
+	viper.BindPFlag("retry-max-wait", rootCmd.PersistentFlags().Lookup("retry-max-wait"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("cache-file", rootCmd.PersistentFlags().Lookup("cache-file"))
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.todoClient.yaml)")
 
@@ -58,6 +82,14 @@ func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// setClientConfig applies the request flags to the API client
+func setClientConfig() {
+	clientCfg.Timeout = viper.GetDuration("timeout")
+	clientCfg.Retries = viper.GetInt("retries")
+	clientCfg.RetryWait = viper.GetDuration("retry-wait")
+	clientCfg.MaxRetryWait = viper.GetDuration("retry-max-wait")
+}
+
 func initConfig() {
 	if cfgFile != "" {
 		// Use config file from the flag.
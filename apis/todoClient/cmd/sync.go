@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:          "sync",
+	Short:        "Send changes made while offline to the API",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiRoot := viper.GetString("api-root")
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
+
+		return syncAction(os.Stdout, apiRoot, cacheFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
+
+func syncAction(out io.Writer, apiRoot, cacheFile string) error {
+	if cacheFile == "" {
+		return fmt.Errorf("%w: No cache file configured", ErrInvalid)
+	}
+
+	c, err := loadCache(cacheFile)
+	if err != nil {
+		return err
+	}
+
+	// tasks added offline get a new CreatedAt on the server. Track them
+	// so later changes to the same task can still find it
+	added := map[itemKey]item{}
+	synced := 0
+	conflicts := []string{}
+
+	for len(c.Pending) > 0 {
+		op := c.Pending[0]
+
+		conflict, err := replay(apiRoot, op, added)
+		if errors.Is(err, ErrConnection) {
+			// keep the remaining changes for the next sync
+			if serr := c.save(cacheFile); serr != nil {
+				return serr
+			}
+			return err
+		}
+
+		// the server rejected the change, so retrying it would block
+		// every later sync
+		if err != nil {
+			conflict = fmt.Sprintf("%s %d: %s", op.Op, op.ID, strings.TrimSpace(err.Error()))
+		}
+
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		} else {
+			synced++
+		}
+
+		c.Pending = c.Pending[1:]
+		if err := c.save(cacheFile); err != nil {
+			return err
+		}
+	}
+
+	items, err := getAllOrEmpty(apiRoot)
+	if err != nil {
+		return err
+	}
+
+	c.refresh(items)
+	if err := c.save(cacheFile); err != nil {
+		return err
+	}
+
+	return printSync(out, synced, conflicts)
+}
+
+// replay sends a single queued change to the API. It returns a
+// description of the conflict if the change no longer applies to the
+// list on the server. Errors mean the change wasn't applied
+func replay(apiRoot string, op pendingOp, added map[itemKey]item) (string, error) {
+	if op.Op == opAdd {
+		if err := addItem(apiRoot, op.Item.Task); err != nil {
+			return "", err
+		}
+
+		// the task is on the server now, so it must not be sent again
+		// even if it can't be tracked. The API appends new tasks to the
+		// end of the list
+		if items, err := getAllOrEmpty(apiRoot); err == nil && len(items) > 0 {
+			added[keyOf(op.Item)] = items[len(items)-1]
+		}
+		return "", nil
+	}
+
+	items, err := getAllOrEmpty(apiRoot)
+	if err != nil {
+		return "", err
+	}
+
+	target := op.Item
+	if i, ok := added[keyOf(target)]; ok {
+		target = i
+	}
+
+	id := findItem(items, op.ID, target)
+	if id == 0 {
+		return fmt.Sprintf("%s %d: task %q no longer exists on the server",
+			op.Op, op.ID, op.Item.Task), nil
+	}
+
+	switch op.Op {
+	case opComplete:
+		if items[id-1].Done {
+			return fmt.Sprintf("%s %d: task %q already completed on the server",
+				op.Op, op.ID, op.Item.Task), nil
+		}
+		err = completeItem(apiRoot, id)
+	case opDelete:
+		err = deleteItem(apiRoot, id)
+	default:
+		return fmt.Sprintf("%s %d: unknown operation", op.Op, op.ID), nil
+	}
+
+	return "", err
+}
+
+// findItem returns the current ID of target in items, or 0 if it is gone.
+// The ID the change was queued with is checked first
+func findItem(items []item, id int, target item) int {
+	k := keyOf(target)
+
+	if id > 0 && id <= len(items) && keyOf(items[id-1]) == k {
+		return id
+	}
+
+	for i := range items {
+		if keyOf(items[i]) == k {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// getAllOrEmpty fetches all items, treating an empty list as a result
+// rather than an error
+func getAllOrEmpty(apiRoot string) ([]item, error) {
+	items, err := getAll(apiRoot)
+	if errors.Is(err, ErrNotFound) {
+		return []item{}, nil
+	}
+	return items, err
+}
+
+func printSync(out io.Writer, synced int, conflicts []string) error {
+	fmt.Fprintf(out, "Synced %d change(s).\n", synced)
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(out, "%d conflict(s):\n", len(conflicts))
+	for _, c := range conflicts {
+		fmt.Fprintf(out, "  %s\n", c)
+	}
+
+	return fmt.Errorf("%w: %d change(s) not applied", ErrConflict, len(conflicts))
+}
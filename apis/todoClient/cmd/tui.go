@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+	"todoClient/app"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:          "tui",
+	Short:        "Manage the todo list in an interactive terminal interface",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiRoot := viper.GetString("api-root")
+		interval := viper.GetDuration("refresh")
+		if interval <= 0 {
+			return fmt.Errorf("%w: --refresh must be greater than zero, got %s",
+				ErrInvalid, interval)
+		}
+
+		return tuiAction(apiRoot, interval)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+
+	tuiCmd.Flags().Duration("refresh", 30*time.Second,
+		"How often the list is reloaded from the API")
+
+	viper.BindPFlag("refresh", tuiCmd.Flags().Lookup("refresh"))
+}
+
+func tuiAction(apiRoot string, interval time.Duration) error {
+	a, err := app.New(tuiClient{apiRoot: apiRoot}, interval)
+	if err != nil {
+		return err
+	}
+
+	return a.Run()
+}
+
+// tuiClient connects the terminal interface to the API
+type tuiClient struct {
+	apiRoot string
+}
+
+func (c tuiClient) List() ([]app.Item, error) {
+	items, err := getAllOrEmpty(c.apiRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]app.Item, len(items))
+	for k, i := range items {
+		list[k] = app.Item{
+			Task:        i.Task,
+			Done:        i.Done,
+			CreatedAt:   i.CreatedAt,
+			CompletedAt: i.CompletedAt,
+		}
+	}
+
+	return list, nil
+}
+
+func (c tuiClient) Add(task string) error {
+	return addItem(c.apiRoot, task)
+}
+
+func (c tuiClient) Complete(id int) error {
+	return completeItem(c.apiRoot, id)
+}
+
+func (c tuiClient) Delete(id int) error {
+	return deleteItem(c.apiRoot, id)
+}
@@ -23,8 +23,17 @@ var viewCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// get the api-root configuration value from Viper and
 		apiRoot := viper.GetString("api-root")
+		cacheFile, err := cacheFilePath()
+		if err != nil {
+			return err
+		}
 
-		return viewAction(os.Stdout, apiRoot, args[0])
+		p, err := newPrinter(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
+
+		return viewAction(os.Stdout, p, apiRoot, cacheFile, args[0])
 	},
 }
 
@@ -42,7 +51,7 @@ func init() {
 	// viewCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func viewAction(out io.Writer, apiRoot, arg string) error {
+func viewAction(out io.Writer, p *printer, apiRoot, cacheFile, arg string) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
@@ -50,9 +59,25 @@ func viewAction(out io.Writer, apiRoot, arg string) error {
 
 	i, err := getOne(apiRoot, id)
 	if err != nil {
-		return err
+		if !isOffline(err, cacheFile) {
+			return err
+		}
+
+		c, err := loadCache(cacheFile)
+		if err != nil {
+			return err
+		}
+		if id <= 0 || id > len(c.Items) {
+			return fmt.Errorf("%w: Item %d not in cache", ErrNotFound, id)
+		}
+		if p.isTable() {
+			if err := printOffline(out, c); err != nil {
+				return err
+			}
+		}
+		return p.item(out, id, c.Items[id-1])
 	}
-	return printOne(out, i)
+	return p.item(out, id, i)
 }
 
 func printOne(out io.Writer, i item) error {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 	"todo"
 )
 
@@ -16,6 +17,8 @@ var (
 )
 
 func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
+	keys := addKeys{}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		list := &todo.List{}
 
@@ -31,7 +34,7 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 			case http.MethodGet:
 				getAllHandler(w, r, list)
 			case http.MethodPost:
-				addHandler(w, r, list, todoFile)
+				addHandler(w, r, list, todoFile, keys)
 			default:
 				message := "Method not supported"
 				replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -112,7 +115,14 @@ func patchHandler(w http.ResponseWriter, r *http.Request,
 }
 
 func addHandler(w http.ResponseWriter, r *http.Request,
-	list *todo.List, todoFile string) {
+	list *todo.List, todoFile string, keys addKeys) {
+
+	// a retried request with a known key was already added
+	key := r.Header.Get("Idempotency-Key")
+	if key != "" && keys.seen(key) {
+		replyTextContent(w, r, http.StatusCreated, "")
+		return
+	}
 
 	item := struct {
 		Task string `json:"task"`
@@ -130,9 +140,36 @@ func addHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if key != "" {
+		keys.add(key)
+	}
+
 	replyTextContent(w, r, http.StatusCreated, "")
 }
 
+// addKeysTTL is how long the Idempotency-Key of an add request is kept
+const addKeysTTL = 24 * time.Hour
+
+// addKeys holds the Idempotency-Key of recent add requests so a client
+// retrying a request does not add the same task twice. Access is guarded
+// by the router lock
+type addKeys map[string]time.Time
+
+func (k addKeys) seen(key string) bool {
+	t, ok := k[key]
+	return ok && time.Since(t) < addKeysTTL
+}
+
+func (k addKeys) add(key string) {
+	now := time.Now()
+	for old, t := range k {
+		if now.Sub(t) >= addKeysTTL {
+			delete(k, old)
+		}
+	}
+	k[key] = now
+}
+
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		replyError(w, r, http.StatusNotFound, "")
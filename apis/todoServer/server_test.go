@@ -292,3 +292,45 @@ func setupAPI(t *testing.T) (string, func()) {
 		os.Remove(tempTodoFile.Name())
 	}
 }
+
+func TestAddIdempotent(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	// Send the same request twice as a client would when retrying
+	for i := 0; i < 2; i++ {
+		body := strings.NewReader(`{"task":"Task number 3."}`)
+		req, err := http.NewRequest(http.MethodPost, url+"/todo", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		if r.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected %q, got %q.",
+				http.StatusText(http.StatusCreated), http.StatusText(r.StatusCode))
+		}
+	}
+
+	r, err := http.Get(url + "/todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp todoResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+
+	if len(resp.Results) != 3 {
+		t.Errorf("Expected 3 items, got %d.", len(resp.Results))
+	}
+}
@@ -0,0 +1,112 @@
+// Package alert delivers notifications about changes found by
+// consecutive scans
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"pScan/scan"
+	"time"
+
+	"notify"
+)
+
+// Alert describes the changes between two scans
+type Alert struct {
+	Time     time.Time
+	Previous scan.Record
+	Current  scan.Record
+	Diff     scan.Diff
+}
+
+// Title is a short summary of the alert
+func (a Alert) Title() string {
+	return fmt.Sprintf("pScan: %d hosts, %d ports changed",
+		len(a.Diff.HostsAppeared)+len(a.Diff.HostsDisappeared),
+		len(a.Diff.Opened)+len(a.Diff.Closed))
+}
+
+// Sink delivers alerts
+type Sink interface {
+	Send(a Alert) error
+}
+
+// Writer prints alerts to an io.Writer such as STDOUT
+type Writer struct {
+	Out io.Writer
+}
+
+// Send prints the changes in a
+func (w *Writer) Send(a Alert) error {
+	_, err := fmt.Fprintf(w.Out, "%s %s\n%s", a.Time.Format(time.RFC3339),
+		a.Title(), a.Diff)
+	return err
+}
+
+// Webhook posts alerts as JSON to a URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhook creates a webhook sink for url
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// webhookPayload is the body posted by Webhook
+type webhookPayload struct {
+	Time             time.Time         `json:"time"`
+	Title            string            `json:"title"`
+	Message          string            `json:"message"`
+	PreviousScan     int               `json:"previous_scan,omitempty"`
+	CurrentScan      int               `json:"current_scan,omitempty"`
+	HostsAppeared    []string          `json:"hosts_appeared,omitempty"`
+	HostsDisappeared []string          `json:"hosts_disappeared,omitempty"`
+	Opened           []scan.PortChange `json:"opened,omitempty"`
+	Closed           []scan.PortChange `json:"closed,omitempty"`
+}
+
+// Send posts a to the webhook URL. Responses other than 2xx are errors
+func (w *Webhook) Send(a Alert) error {
+	body, err := json.Marshal(webhookPayload{
+		Time:             a.Time,
+		Title:            a.Title(),
+		Message:          a.Diff.String(),
+		PreviousScan:     a.Previous.ID,
+		CurrentScan:      a.Current.ID,
+		HostsAppeared:    a.Diff.HostsAppeared,
+		HostsDisappeared: a.Diff.HostsDisappeared,
+		Opened:           a.Diff.Opened,
+		Closed:           a.Diff.Closed,
+	})
+	if err != nil {
+		return err
+	}
+
+	r, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %s", w.URL, r.Status)
+	}
+
+	return nil
+}
+
+// Desktop shows alerts as desktop notifications
+type Desktop struct{}
+
+// Send shows a notification with the changes in a
+func (Desktop) Send(a Alert) error {
+	return notify.New(a.Title(), a.Diff.String(), notify.SeverityNormal).Send()
+}
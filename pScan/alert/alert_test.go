@@ -0,0 +1,93 @@
+package alert_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pScan/alert"
+	"pScan/scan"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testAlert() alert.Alert {
+	return alert.Alert{
+		Time:     time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Previous: scan.Record{ID: 1},
+		Current:  scan.Record{ID: 2},
+		Diff: scan.Diff{
+			HostsAppeared: []string{"host2"},
+			Opened:        []scan.PortChange{{Host: "host1", Port: 80, Proto: scan.TCP}},
+			Closed:        []scan.PortChange{{Host: "host1", Port: 22, Proto: scan.TCP}},
+		},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &alert.Writer{Out: &out}
+
+	if err := w.Send(testAlert()); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	expected := "2023-01-02T03:04:05Z pScan: 1 hosts, 2 ports changed\n" +
+		"+ host2: host appeared\n" +
+		"+ host1: 80/tcp opened\n" +
+		"- host1: 22/tcp closed\n"
+
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q instead\n", expected, out.String())
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{"OK", http.StatusOK, false},
+		{"NoContent", http.StatusNoContent, false},
+		{"Error", http.StatusInternalServerError, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body map[string]interface{}
+
+			ts := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodPost {
+						t.Errorf("Expected method POST, got %s instead\n", r.Method)
+					}
+					if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+						t.Errorf("Expected JSON content, got %q instead\n", ct)
+					}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Error(err)
+					}
+					w.WriteHeader(tc.status)
+				}))
+			defer ts.Close()
+
+			err := alert.NewWebhook(ts.URL).Send(testAlert())
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v instead\n", tc.expectErr, err)
+			}
+
+			if body["current_scan"] != 2.0 || body["previous_scan"] != 1.0 {
+				t.Errorf("Expected scans 1 and 2 in body, got %v instead\n", body)
+			}
+
+			expOpened := []interface{}{
+				map[string]interface{}{"host": "host1", "port": 80.0, "proto": "tcp"},
+			}
+			if !reflect.DeepEqual(body["opened"], expOpened) {
+				t.Errorf("Expected opened %v, got %v instead\n", expOpened, body["opened"])
+			}
+		})
+	}
+}
@@ -2,14 +2,27 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"pScan/alert"
 	"pScan/scan"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
 )
 
 func setup(t *testing.T, hosts []string, initList bool) (string, func()) {
@@ -39,6 +52,11 @@ func setup(t *testing.T, hosts []string, initList bool) (string, func()) {
 	}
 }
 
+// addHosts adds hosts without metadata
+func addHosts(out io.Writer, hostsFile string, args []string) error {
+	return addAction(out, hostsFile, args, scan.HostInfo{})
+}
+
 func TestHostActions(t *testing.T) {
 	// Define hosts for actions test
 	hosts := []string{
@@ -59,7 +77,7 @@ func TestHostActions(t *testing.T) {
 			args:           hosts,
 			expectedOut:    "Added host: host1\nAdded host: host2\nAdded host: host3\n",
 			initList:       false,
-			actionFunction: addAction,
+			actionFunction: addHosts,
 		},
 		{
 			name:           "ListAction",
@@ -97,6 +115,148 @@ func TestHostActions(t *testing.T) {
 	}
 }
 
+func TestInvalidHostActions(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	// a hosts file written before names were validated
+	if err := os.WriteFile(tf, []byte("host1\nmy_db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := listAction(&out, tf, nil); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+	if exp := "host1\nmy_db\n"; out.String() != exp {
+		t.Errorf("Expected output %q, got %q\n", exp, out.String())
+	}
+
+	out.Reset()
+	if err := deleteAction(&out, tf, []string{"my_db"}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	out.Reset()
+	if err := listAction(&out, tf, nil); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+	if exp := "host1\n"; out.String() != exp {
+		t.Errorf("Expected output %q, got %q\n", exp, out.String())
+	}
+}
+
+func TestImportExportActions(t *testing.T) {
+	tf, cleanup := setup(t, []string{"host1"}, true)
+	defer cleanup()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"hosts.csv": "host,group\nhost1,web\nweb2,web\n",
+		"hosts":     "127.0.0.1 localhost\n10.0.0.5 db1\n",
+	}
+	args := []string{}
+	for name, content := range files {
+		f := filepath.Join(dir, name)
+		if err := os.WriteFile(f, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, f)
+	}
+	sort.Strings(args)
+
+	var out bytes.Buffer
+	if err := importAction(&out, tf, args, formatAuto); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	expectedOut := "Added host: 10.0.0.5\n" +
+		"Skipped 127.0.0.1: loopback address\n" +
+		"Added host: web2\n" +
+		"Skipped host1: already in the list\n" +
+		"Imported 2 hosts, skipped 2\n"
+	if out.String() != expectedOut {
+		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
+	}
+
+	out.Reset()
+	if err := exportAction(&out, tf, scan.FormatCSV); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	expectedOut = "host,group,tags,ports,notes\nhost1,,,,\n10.0.0.5,,,,db1\nweb2,web,,,\n"
+	if out.String() != expectedOut {
+		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
+	}
+
+	if err := importAction(io.Discard, tf, args, "xls"); !errors.Is(err, scan.ErrInvalidFormat) {
+		t.Errorf("Expected error %q, got %q\n", scan.ErrInvalidFormat, err)
+	}
+}
+
+// TestCommandFlags runs the commands through rootCmd, so flags clashing
+// with those of their parents are caught
+func TestCommandFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tf, cleanup := setup(t, []string{"host1"}, true)
+	defer cleanup()
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "hosts.csv")
+	if err := os.WriteFile(csvFile, []byte("host\nweb2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outFile := filepath.Join(dir, "hosts.txt")
+
+	// --help stays set once given, so the commands that do something run
+	// first
+	commands := [][]string{
+		{"hosts", "import", "-f", tf, "--format", "csv", csvFile},
+		{"hosts", "export", "-f", tf, "--format", scan.FormatPlain, outFile},
+		// completion visits the flags of every command
+		{"completion", "bash"},
+	}
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		commands = append(commands, append(strings.Fields(cmd.CommandPath())[1:], "--help"))
+		for _, c := range cmd.Commands() {
+			walk(c)
+		}
+	}
+	walk(rootCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		for _, args := range commands {
+			if cmd, _, err := rootCmd.Find(args); err == nil {
+				cmd.Flags().Set("help", "false")
+			}
+		}
+	}()
+
+	for _, args := range commands {
+		out.Reset()
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Expected no error running %q, got %q: %s\n", args, err, out.String())
+		}
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "host1\nweb2\n"; string(data) != exp {
+		t.Errorf("Expected export %q, got %q instead\n", exp, string(data))
+	}
+}
+
 func TestIntegration(t *testing.T) {
 	// Define hosts for integration test
 	hosts := []string{
@@ -135,7 +295,7 @@ func TestIntegration(t *testing.T) {
 
 	// add -> list -> delete -> list
 	// Add hosts to the list
-	if err := addAction(&out, tf, hosts); err != nil {
+	if err := addAction(&out, tf, hosts, scan.HostInfo{}); err != nil {
 		t.Fatalf("Expected no error, got %q\n", err)
 	}
 
@@ -155,7 +315,9 @@ func TestIntegration(t *testing.T) {
 	}
 
 	// Scan hosts
-	if err := scanAction(&out, tf, nil); err != nil {
+	err := scanAction(context.Background(), &out, nil, tf, "", nil,
+		scan.Options{Workers: 10}, "table")
+	if err != nil {
 		t.Fatalf("Expected no error, got %q\n", err)
 	}
 
@@ -213,7 +375,9 @@ func TestScanAction(t *testing.T) {
 	var out bytes.Buffer
 
 	// Execute scan and capture output
-	if err := scanAction(&out, tf, ports); err != nil {
+	err := scanAction(context.Background(), &out, nil, tf, "", ports,
+		scan.Options{Workers: 10, Family: scan.IPv4}, "table")
+	if err != nil {
 		t.Fatalf("Expected no error, got %q\n", err)
 	}
 
@@ -222,3 +386,663 @@ func TestScanAction(t *testing.T) {
 		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
 	}
 }
+
+func TestPrintResults(t *testing.T) {
+	results := []scan.Results{
+		{
+			Host: "host1",
+			PortStates: []scan.PortState{
+				{Port: 22, Proto: scan.TCP, State: scan.StateOpen,
+					Fingerprint: &scan.Fingerprint{Service: "ssh", Version: "OpenSSH_8.9p1"}},
+				{Port: 40000, Proto: scan.TCP},
+				{Port: 8080, Proto: scan.TCP, State: scan.StateFiltered},
+				{Port: 123, Proto: scan.UDP, State: scan.StateOpenFiltered},
+				{Port: 40000, Proto: scan.UDP},
+			},
+		},
+		{Host: "host2", NotFound: true},
+		{Host: "host3", Down: true},
+		{Host: "10.0.0.1", Address: "10.0.0.1", Names: []string{"gw.example.com"},
+			PortStates: []scan.PortState{{Port: 22, Proto: scan.TCP}}},
+	}
+
+	expectedOut := "host1:\n\t22 (ssh): open  ssh OpenSSH_8.9p1\n\t40000: closed\n"
+	expectedOut += "\t8080 (http-proxy): filtered\n"
+	expectedOut += "\t123/udp (ntp): open|filtered\n\t40000/udp: closed\n\n"
+	expectedOut += "host2: Host not found\n\n"
+	expectedOut += "host3: Host down\n\n"
+	expectedOut += "10.0.0.1 [gw.example.com]:\n\t22 (ssh): closed\n\n"
+
+	var out bytes.Buffer
+	if err := printResults(&out, results); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if out.String() != expectedOut {
+		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
+	}
+}
+
+func TestScanOutput(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	ports := struct{ Open, Closed int }{
+		Open:   open.Addr().(*net.TCPAddr).Port,
+		Closed: closed.Addr().(*net.TCPAddr).Port,
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+	hl.Add("unknownhostoutthere")
+
+	results := scan.Run(hl, []int{ports.Open, ports.Closed}, scan.Options{Workers: 10})
+
+	// latency and timestamps change on every run
+	for i := range results {
+		for j := range results[i].PortStates {
+			results[i].PortStates[j].Latency = 1500 * time.Microsecond
+		}
+	}
+	results[0].PortStates[0].Fingerprint = &scan.Fingerprint{
+		Service: "http",
+		Version: "nginx/1.25.3",
+		Banner:  "HTTP/1.1 200 OK",
+	}
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	results[0].PortStates[0].TLS = &scan.TLSInfo{
+		Version:   "TLS 1.3",
+		ALPN:      "h2",
+		Subject:   "CN=example.com",
+		Issuer:    "CN=Example CA",
+		SANs:      []string{"example.com", "www.example.com"},
+		NotBefore: start.AddDate(0, -1, 0),
+		NotAfter:  start.AddDate(0, 2, 0),
+	}
+	report := newScanReport(results, start, start.Add(2*time.Second))
+
+	for _, output := range []string{"table", "json", "csv", "xml"} {
+		t.Run(output, func(t *testing.T) {
+			// golden files use template fields for the listener ports
+			goldenFile := filepath.Join("testdata", "scan."+output)
+			tpl, err := template.ParseFiles(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected bytes.Buffer
+			if err := tpl.Execute(&expected, ports); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := printReport(&out, output, report, results); err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			if !bytes.Equal(expected.Bytes(), out.Bytes()) {
+				t.Logf("golden:\n%s\n", expected.String())
+				t.Logf("result:\n%s\n", out.String())
+				t.Errorf("Result content does not match golden file %s", goldenFile)
+			}
+		})
+	}
+}
+
+func TestScanActionInvalidOutput(t *testing.T) {
+	err := scanAction(context.Background(), io.Discard, nil, "", "", nil,
+		scan.Options{}, "yaml")
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("Expected error %q, got %q\n", ErrInvalidOutput, err)
+	}
+}
+
+func TestHistoryActions(t *testing.T) {
+	tf, cleanup := setup(t, []string{"127.0.0.1", "unknownhostoutthere"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.history")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	opts := scan.Options{Workers: 10}
+	ctx := context.Background()
+
+	// scan once with the port open and once with it closed
+	if err := scanAction(ctx, io.Discard, nil, tf, historyFile, []int{port}, opts, "table"); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+	ln.Close()
+	if err := scanAction(ctx, io.Discard, nil, tf, historyFile, []int{port}, opts, "table"); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	var out bytes.Buffer
+	if err := historyListAction(&out, historyFile); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 scans in history, got %q instead\n", out.String())
+	}
+	for i, expSuffix := range []string{"\t2 hosts\t1 open ports", "\t2 hosts\t0 open ports"} {
+		if !strings.HasPrefix(lines[i], fmt.Sprintf("%d\t", i+1)) ||
+			!strings.HasSuffix(lines[i], expSuffix) {
+			t.Errorf("Unexpected history line %q\n", lines[i])
+		}
+	}
+
+	out.Reset()
+	if err := diffAction(&out, historyFile, []string{"1", "2"}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	expDiff := fmt.Sprintf("- 127.0.0.1: %d/tcp closed\n", port)
+	if !strings.HasPrefix(out.String(), "Scan 1 (") ||
+		!strings.HasSuffix(out.String(), expDiff) {
+		t.Errorf("Expected diff ending with %q, got %q instead\n", expDiff, out.String())
+	}
+
+	out.Reset()
+	if err := diffAction(&out, historyFile, []string{"2", "2"}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+	if !strings.HasSuffix(out.String(), "No changes\n") {
+		t.Errorf("Expected no changes, got %q instead\n", out.String())
+	}
+
+	for _, args := range [][]string{{"1", "3"}, {"one", "2"}} {
+		err := diffAction(io.Discard, historyFile, args)
+		if !errors.Is(err, scan.ErrScanNotFound) {
+			t.Errorf("Expected error %q for %v, got %q\n", scan.ErrScanNotFound, args, err)
+		}
+	}
+}
+
+func TestCertsAction(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+	// the connection scanning the port is closed before the handshake
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.history")
+
+	if err := certsAction(io.Discard, historyFile, nil, 30, time.Now()); !errors.Is(err, scan.ErrScanNotFound) {
+		t.Errorf("Expected error %q, got %q\n", scan.ErrScanNotFound, err)
+	}
+
+	port := ts.Listener.Addr().(*net.TCPAddr).Port
+	opts := scan.Options{Workers: 1, TLS: true}
+	if err := scanAction(context.Background(), io.Discard, nil, tf, historyFile,
+		[]int{port}, opts, "table"); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	cert := ts.Certificate()
+	day := 24 * time.Hour
+
+	testCases := []struct {
+		name       string
+		args       []string
+		now        time.Time
+		expectMark string
+		expectSum  string
+	}{
+		{"Valid", nil, cert.NotBefore, "", "0 of 1 certificates"},
+		{"Expiring", []string{"1"}, cert.NotAfter.Add(-10 * day), "\tEXPIRES IN 10 DAYS",
+			"1 of 1 certificates"},
+		{"Expired", nil, cert.NotAfter.Add(day), "\tEXPIRED", "1 of 1 certificates"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := certsAction(&out, historyFile, tc.args, 30, tc.now); err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			lines := strings.Split(out.String(), "\n")
+			if len(lines) < 2 {
+				t.Fatalf("Expected a certificate line, got %q instead\n", out.String())
+			}
+
+			expLine := fmt.Sprintf("127.0.0.1:%d\tTLS 1.3\t%s\t%s%s", port, cert.Subject,
+				cert.NotAfter.Format(timeFormat), tc.expectMark)
+			if lines[1] != expLine {
+				t.Errorf("Expected line %q, got %q instead\n", expLine, lines[1])
+			}
+
+			if !strings.Contains(out.String(), tc.expectSum) {
+				t.Errorf("Expected summary %q, got %q instead\n", tc.expectSum, out.String())
+			}
+		})
+	}
+}
+
+// chanSink sends alerts to a channel so tests can wait for them
+type chanSink chan alert.Alert
+
+func (c chanSink) Send(a alert.Alert) error {
+	c <- a
+	return nil
+}
+
+func TestWatchAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.history")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alerts := make(chanSink, 1)
+	var out bytes.Buffer
+	done := make(chan error)
+
+	go func() {
+		done <- watchAction(ctx, &out, tf, historyFile, []int{port},
+			scan.Options{Workers: 10}, 20*time.Millisecond, []alert.Sink{alerts})
+	}()
+
+	// close the port once the first scan is saved
+	for {
+		// the file may be read while the scan is being written
+		h := &scan.History{}
+		if err := h.Load(historyFile); err == nil && len(h.Records) > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	ln.Close()
+
+	select {
+	case a := <-alerts:
+		exp := []scan.PortChange{{Host: "127.0.0.1", Port: port, Proto: scan.TCP}}
+		if !reflect.DeepEqual(a.Diff.Closed, exp) {
+			t.Errorf("Expected closed ports %v, got %v instead\n", exp, a.Diff.Closed)
+		}
+		if a.Current.ID != a.Previous.ID+1 {
+			t.Errorf("Expected consecutive scans, got %d and %d instead\n",
+				a.Previous.ID, a.Current.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for alert")
+	}
+
+	cancel()
+	// drain alerts sent while stopping
+	go func() {
+		for range alerts {
+		}
+	}()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if !strings.HasSuffix(out.String(), "Stopped watching\n") {
+		t.Errorf("Expected watch to stop, got %q instead\n", out.String())
+	}
+}
+
+func TestWatchInvalidInterval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer watchCmd.Flags().Set("interval", "10m")
+
+	tf, cleanup := setup(t, []string{"127.0.0.1"}, true)
+	defer cleanup()
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	}()
+
+	for _, interval := range []string{"0", "-1s"} {
+		t.Run(interval, func(t *testing.T) {
+			rootCmd.SetArgs([]string{"watch", "-f", tf, "--history-file", "",
+				"--interval", interval})
+
+			if err := rootCmd.Execute(); !errors.Is(err, ErrInvalidInterval) {
+				t.Errorf("Expected error %q, got %q instead\n", ErrInvalidInterval, err)
+			}
+		})
+	}
+}
+
+func TestNewSinks(t *testing.T) {
+	testCases := []struct {
+		name      string
+		specs     []string
+		expectLen int
+		expectErr error
+	}{
+		{"All", []string{"stdout", "webhook=http://localhost/hook", "desktop"}, 3, nil},
+		{"WebhookNoURL", []string{"webhook"}, 0, ErrInvalidAlert},
+		{"Unknown", []string{"email"}, 0, ErrInvalidAlert},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sinks, err := newSinks(io.Discard, tc.specs)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if len(sinks) != tc.expectLen {
+				t.Errorf("Expected %d sinks, got %d instead\n", tc.expectLen, len(sinks))
+			}
+		})
+	}
+}
+
+func TestHostInfoActions(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	info := scan.HostInfo{Group: "web", Tags: []string{"prod"}, Notes: "Public site"}
+	if err := addAction(&out, tf, []string{"host1"}, info); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+	if err := addAction(&out, tf, []string{"host2"}, scan.HostInfo{}); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	out.Reset()
+	if err := listAction(&out, tf, nil); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	expectedOut := "host1\tgroup=web tags=prod notes=\"Public site\"\nhost2\n"
+	if out.String() != expectedOut {
+		t.Errorf("Expected output %q, got %q\n", expectedOut, out.String())
+	}
+
+	out.Reset()
+	opts := scan.Options{Workers: 1, Group: "web"}
+	err := scanAction(context.Background(), &out, nil, tf, "", []int{}, opts, "table")
+	if err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	if out.String() != "host1: Host not found\n\n" {
+		t.Errorf("Expected only host1 to be scanned, got %q\n", out.String())
+	}
+}
+
+func TestScanActionInterrupted(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.history")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	err := scanAction(ctx, &out, nil, tf, historyFile, []int{80},
+		scan.Options{Workers: 1}, "json")
+	if !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected error %q, got %q\n", ErrInterrupted, err)
+	}
+
+	if !strings.Contains(out.String(), `"hosts": []`) {
+		t.Errorf("Expected empty partial results, got %q\n", out.String())
+	}
+
+	if _, err := os.Stat(historyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected interrupted scan not to be saved, got %v\n", err)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	bar := &progressBar{out: &out, every: time.Hour}
+
+	// only the first and the last updates are drawn within the interval
+	for i := 1; i <= 4; i++ {
+		bar.update(scan.PortResult{Done: i, Total: 4})
+	}
+	bar.clear()
+
+	expected := "\r[#######.......................] 1/4 ports  25%" +
+		"\r[##############################] 4/4 ports 100%" +
+		"\r\033[K"
+
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q\n", expected, out.String())
+	}
+}
+
+func TestDiscoverAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"127.0.0.1", "unknownhostoutthere"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	opts := scan.Options{
+		Workers:        10,
+		DiscoveryPorts: []int{ln.Addr().(*net.TCPAddr).Port},
+	}
+
+	var out bytes.Buffer
+	if err := discoverAction(context.Background(), &out, tf, opts); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %q instead\n", out.String())
+	}
+
+	// latency and the probe that answers first change on every run
+	if !strings.HasPrefix(lines[0], "127.0.0.1: up (") {
+		t.Errorf("Expected 127.0.0.1 up, got %q instead\n", lines[0])
+	}
+
+	expected := "unknownhostoutthere: Host not found\n\n1 of 2 hosts up\n"
+	if rest := strings.Join(lines[1:], "\n"); rest != expected {
+		t.Errorf("Expected output %q, got %q instead\n", expected, rest)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	config := `profile: quick
+hosts-file: all.hosts
+timeout: 2s
+profiles:
+  quick:
+    ports: [22, 80-81]
+    timeout: 300ms
+    output: json
+  lab:
+    hosts-file: lab.hosts
+    workers: 5
+    randomize: true
+  broken:
+    timeout: 5
+  v6:
+    ipv6: true
+  both:
+    ipv4: true
+    ipv6: true
+`
+	// the file is found in the home directory without --config too
+	cfgFile := filepath.Join(dir, configName)
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		args      []string
+		env       map[string]string
+		expFlags  map[string]string
+		expErr    error
+		noDefault bool
+	}{
+		{name: "NoConfig", args: nil, noDefault: true,
+			expFlags: map[string]string{"ports": "[2,80,443]", "hosts-file": "pScan.hosts",
+				"timeout": "1s"}},
+		{name: "DefaultProfile", args: []string{"--config", cfgFile},
+			expFlags: map[string]string{"ports": "[22,80-81]", "hosts-file": "all.hosts",
+				"timeout": "300ms", "output": "json"}},
+		{name: "Profile", args: []string{"--config", cfgFile, "--profile", "lab"},
+			expFlags: map[string]string{"ports": "[2,80,443]", "hosts-file": "lab.hosts",
+				"timeout": "2s", "workers": "5", "randomize": "true"}},
+		{name: "EnvProfile", args: []string{"--config", cfgFile},
+			env:      map[string]string{"PSCAN_PROFILE": "lab"},
+			expFlags: map[string]string{"hosts-file": "lab.hosts"}},
+		{name: "Env", args: []string{"--config", cfgFile},
+			env:      map[string]string{"PSCAN_TIMEOUT": "5s", "PSCAN_PORTS": "8080,8443"},
+			expFlags: map[string]string{"ports": "[8080,8443]", "timeout": "5s"}},
+		{name: "Flags", args: []string{"--config", cfgFile, "-t", "10s", "-f", "my.hosts"},
+			env:      map[string]string{"PSCAN_TIMEOUT": "5s"},
+			expFlags: map[string]string{"timeout": "10s", "hosts-file": "my.hosts"}},
+		{name: "HomeConfig", args: nil,
+			expFlags: map[string]string{"timeout": "300ms"}},
+		{name: "MissingProfile", args: []string{"--config", cfgFile, "--profile", "full"},
+			expErr: ErrProfileNotFound},
+		{name: "MissingConfig", args: []string{"--config", filepath.Join(dir, "missing.yaml")},
+			expErr: ErrInvalidConfig},
+		{name: "InvalidValue", args: []string{"--config", cfgFile, "--profile", "broken"},
+			expErr: ErrInvalidConfig},
+		{name: "ExclusiveFlags", args: []string{"--config", cfgFile, "--profile", "both"},
+			expErr: ErrInvalidConfig},
+		{name: "ExclusiveEnv", args: []string{"--config", cfgFile, "--profile", "v6"},
+			env: map[string]string{"PSCAN_IPV4": "true"}, expErr: ErrInvalidConfig},
+		// the command line wins over the other flag of the pair
+		{name: "ExclusiveCommandLine", args: []string{"--config", cfgFile, "--profile", "v6", "-4"},
+			expFlags: map[string]string{"ipv4": "true", "ipv6": "false"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			if tc.noDefault {
+				t.Setenv("HOME", t.TempDir())
+			}
+
+			cmd := &cobra.Command{Use: "scan"}
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().String("profile", "", "")
+			cmd.Flags().StringP("hosts-file", "f", "pScan.hosts", "")
+			cmd.Flags().StringP("output", "o", outputTable, "")
+			addScanFlags(cmd)
+
+			if err := cmd.ParseFlags(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applyConfig(cmd)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Fatalf("Expected error %q, got %q instead\n", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q\n", err)
+			}
+
+			for name, exp := range tc.expFlags {
+				if v := cmd.Flags().Lookup(name).Value.String(); v != exp {
+					t.Errorf("Expected --%s %q, got %q instead\n", name, exp, v)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyConfigHostsCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	config := `profile: quick
+hosts-file: all.hosts
+group: web
+profiles:
+  quick:
+    ports: [22, 80]
+    tag: [prod]
+`
+	cfgFile := filepath.Join(t.TempDir(), configName)
+	if err := os.WriteFile(cfgFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &cobra.Command{Use: "pScan"}
+	root.PersistentFlags().String("config", "", "")
+	root.PersistentFlags().String("profile", "", "")
+	root.PersistentFlags().StringP("hosts-file", "f", "pScan.hosts", "")
+
+	add := &cobra.Command{Use: "add"}
+	add.Flags().StringP("group", "g", "", "")
+	add.Flags().StringSlice("tag", nil, "")
+	add.Flags().StringSliceP("ports", "p", nil, "")
+	root.AddCommand(add)
+
+	if err := add.ParseFlags([]string{"--config", cfgFile}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(add); err != nil {
+		t.Fatalf("Expected no error, got %q\n", err)
+	}
+
+	// the ports to scan aren't the ports stored with the hosts
+	expFlags := map[string]string{"hosts-file": "all.hosts", "ports": "[]", "group": "",
+		"tag": "[]"}
+	for name, exp := range expFlags {
+		if v := add.Flags().Lookup(name).Value.String(); v != exp {
+			t.Errorf("Expected --%s %q, got %q instead\n", name, exp, v)
+		}
+	}
+}
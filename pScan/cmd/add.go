@@ -14,9 +14,18 @@ import (
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
-	Use:          "add <host1>...<hostn>",
-	Aliases:      []string{"a"},
-	Short:        "Add new host(s) to list",
+	Use:     "add <host1>...<hostn>",
+	Aliases: []string{"a"},
+	Short:   "Add new host(s) to list",
+	Long: `Add new host(s) to list
+
+	Hosts can be names, IP addresses, CIDR blocks such as 10.0.0.0/28
+	or IPv4 ranges such as 10.0.0.1-20. Blocks and ranges are expanded
+	when the hosts are scanned.
+
+	Use --group, --tag, --ports and --notes to store metadata with the
+	hosts. Scans can then be limited to a group or tag, and hosts with
+	their own ports are scanned on those instead of the --ports of scan.`,
 	SilenceUsage: true,
 	Args:         cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -24,13 +33,37 @@ var addCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return addAction(os.Stdout, hostsFile, args)
+		info := scan.HostInfo{}
+
+		if info.Group, err = cmd.Flags().GetString("group"); err != nil {
+			return err
+		}
+
+		if info.Tags, err = cmd.Flags().GetStringSlice("tag"); err != nil {
+			return err
+		}
+
+		if info.Ports, err = cmd.Flags().GetStringSlice("ports"); err != nil {
+			return err
+		}
+
+		if info.Notes, err = cmd.Flags().GetString("notes"); err != nil {
+			return err
+		}
+
+		return addAction(os.Stdout, hostsFile, args, info)
 	},
 }
 
 func init() {
 	hostsCmd.AddCommand(addCmd)
 
+	addCmd.Flags().StringP("group", "g", "", "group the hosts belong to")
+	addCmd.Flags().StringSlice("tag", nil, "tags for the hosts, can be repeated")
+	addCmd.Flags().StringSliceP("ports", "p", nil,
+		"ports to scan on these hosts instead of the ports given to scan")
+	addCmd.Flags().String("notes", "", "notes about the hosts")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -42,7 +75,7 @@ func init() {
 	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func addAction(out io.Writer, hostsFile string, args []string) error {
+func addAction(out io.Writer, hostsFile string, args []string, info scan.HostInfo) error {
 	hl := &scan.HostsList{}
 
 	if err := hl.Load(hostsFile); err != nil {
@@ -50,7 +83,7 @@ func addAction(out io.Writer, hostsFile string, args []string) error {
 	}
 
 	for _, h := range args {
-		if err := hl.Add(h); err != nil {
+		if err := hl.AddWithInfo(h, info); err != nil {
 			return err
 		}
 
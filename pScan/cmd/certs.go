@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pScan/scan"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// certsCmd represents the certs command
+var certsCmd = &cobra.Command{
+	Use:   "certs [scanID]",
+	Short: "Report the TLS certificates found by a scan",
+	Long: `Lists the TLS certificates found by a scan saved in the history, the
+latest one unless a scan ID is given. Certificates are only inspected
+by scans run with --tls. The version shown is the newest one the port
+negotiated, older versions it may still accept aren't checked.
+
+Certificates that expired or expire within --days are marked, and
+counted at the end of the report.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		days, err := cmd.Flags().GetInt("days")
+		if err != nil {
+			return err
+		}
+
+		return certsAction(os.Stdout, historyFile, args, days, time.Now())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(certsCmd)
+
+	certsCmd.Flags().IntP("days", "d", 30, "mark certificates expiring within this many days")
+}
+
+func certsAction(out io.Writer, historyFile string, args []string, days int,
+	now time.Time) error {
+
+	h := &scan.History{}
+
+	if err := h.Load(historyFile); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if len(h.Records) == 0 {
+			return fmt.Errorf("%w: no scans saved in %s", scan.ErrScanNotFound, historyFile)
+		}
+		return printCerts(out, h.Records[len(h.Records)-1], days, now)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a scan ID", scan.ErrScanNotFound, args[0])
+	}
+
+	r, err := h.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return printCerts(out, r, days, now)
+}
+
+func printCerts(out io.Writer, r scan.Record, days int, now time.Time) error {
+	within := time.Duration(days) * 24 * time.Hour
+	message := fmt.Sprintf("Scan %d (%s):\n", r.ID, r.StartedAt.Format(timeFormat))
+
+	total, expiring := 0, 0
+	for _, res := range r.Results {
+		for _, p := range res.PortStates {
+			t := p.TLS
+			if t == nil {
+				continue
+			}
+			total++
+
+			message += fmt.Sprintf("%s:%d\t%s\t%s\t%s", res.Name(), p.Port, t.Version,
+				t.Subject, t.NotAfter.Format(timeFormat))
+
+			switch {
+			case !t.NotAfter.After(now):
+				message += "\tEXPIRED"
+				expiring++
+			case t.ExpiresWithin(now, within):
+				message += fmt.Sprintf("\tEXPIRES IN %d DAYS", int(t.NotAfter.Sub(now).Hours()/24))
+				expiring++
+			}
+			message += fmt.Sprintln()
+		}
+	}
+
+	if total == 0 {
+		message += fmt.Sprintln("No TLS certificates found, scan with --tls to inspect them")
+	} else {
+		message += fmt.Sprintf("\n%d of %d certificates expired or expire within %d days\n",
+			expiring, total, days)
+	}
+
+	_, err := fmt.Fprint(out, message)
+	return err
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrInvalidConfig   = errors.New("Invalid config")
+	ErrProfileNotFound = errors.New("Profile not found")
+)
+
+// envPrefix starts the environment variables that set flags, such as
+// PSCAN_HOSTS_FILE for --hosts-file
+const envPrefix = "PSCAN"
+
+// mutuallyExclusive is the annotation cobra puts on the flags of a
+// MarkFlagsMutuallyExclusive group, listing the names of the group
+const mutuallyExclusive = "cobra_annotation_mutually_exclusive"
+
+// scanCommands take all their flags from the config. The others give
+// some of the same names another meaning, such as --ports of hosts add,
+// which stores the ports to scan a host on instead of scanning them
+var scanCommands = map[string]bool{
+	"scan":     true,
+	"watch":    true,
+	"discover": true,
+	"serve":    true,
+}
+
+// configName is the config file looked up in the home directory when
+// --config isn't given
+const configName = ".pScan.yaml"
+
+// applyConfig sets the flags of cmd that weren't given on the command
+// line from, in order of precedence, the environment, the selected
+// profile and the top level of the config file. Both the file and the
+// profiles use the flag names as keys:
+//
+//	profile: quick
+//	hosts-file: /etc/pScan/hosts
+//	profiles:
+//	  quick:
+//	    ports: [22, 80, 443]
+//	    timeout: 300ms
+//	  full:
+//	    ports: [1-65535]
+//	    workers: 500
+//	    output: json
+//
+// The profile is chosen with --profile, PSCAN_PROFILE or the profile key
+// of the file. Keys matching no flag of cmd are ignored, so a profile
+// can hold the options of several commands. Commands outside of
+// scanCommands only take the global flags, such as hosts-file
+func applyConfig(cmd *cobra.Command) error {
+	cfgFile, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	file := cfgFile
+	if home, err := os.UserHomeDir(); file == "" && err == nil {
+		file = filepath.Join(home, configName)
+	}
+
+	if file != "" {
+		v.SetConfigType("yaml")
+		v.SetConfigFile(file)
+		// the default file is optional, unlike the one given with --config
+		if err := v.ReadInConfig(); err != nil &&
+			(cfgFile != "" || !errors.Is(err, os.ErrNotExist)) {
+			return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("profile") {
+		profile = v.GetString("profile")
+	}
+
+	if profile != "" {
+		key := "profiles." + profile
+		if !v.IsSet(key) {
+			return fmt.Errorf("%w: %s", ErrProfileNotFound, profile)
+		}
+		if err := v.MergeConfigMap(v.GetStringMap(key)); err != nil {
+			return fmt.Errorf("%w: profile %s: %s", ErrInvalidConfig, profile, err)
+		}
+	}
+
+	excluded := excludedFlags(cmd)
+	global := cmd.InheritedFlags()
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || excluded[f.Name] || f.Name == "config" ||
+			f.Name == "profile" || !v.IsSet(f.Name) {
+			return
+		}
+		if !scanCommands[cmd.Name()] && global.Lookup(f.Name) == nil {
+			return
+		}
+
+		if setErr := cmd.Flags().Set(f.Name, flagValue(v.Get(f.Name))); setErr != nil {
+			err = fmt.Errorf("%w: %s: %s", ErrInvalidConfig, f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// the config may set both flags of a mutually exclusive pair, such as
+	// ipv4 and ipv6, which cobra only checks after this runs
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+
+	return nil
+}
+
+// excludedFlags returns the flags that are mutually exclusive with one
+// given on the command line. The command line wins over the config, so
+// these are left alone
+func excludedFlags(cmd *cobra.Command) map[string]bool {
+	excluded := map[string]bool{}
+
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		for _, group := range f.Annotations[mutuallyExclusive] {
+			for _, name := range strings.Fields(group) {
+				if name != f.Name {
+					excluded[name] = true
+				}
+			}
+		}
+	})
+
+	return excluded
+}
+
+// flagValue formats a config value as it would be given on the command
+// line. Lists become comma separated values
+func flagValue(val any) string {
+	list, ok := val.([]any)
+	if !ok {
+		return fmt.Sprint(val)
+	}
+
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		values = append(values, fmt.Sprint(v))
+	}
+	return strings.Join(values, ",")
+}
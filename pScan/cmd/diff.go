@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pScan/scan"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// timeFormat is how scan times are shown
+const timeFormat = "2006-01-02 15:04:05"
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <scanA> <scanB>",
+	Short: "Show what changed between two scans",
+	Long: `Compares two scans saved in the history, given by the IDs shown by
+pScan history list.
+
+Reports hosts that appeared or disappeared and ports that were opened
+or closed from the first scan to the second.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+		return diffAction(os.Stdout, historyFile, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func diffAction(out io.Writer, historyFile string, args []string) error {
+	h := &scan.History{}
+
+	if err := h.Load(historyFile); err != nil {
+		return err
+	}
+
+	records := []scan.Record{}
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a scan ID", scan.ErrScanNotFound, arg)
+		}
+
+		r, err := h.Get(id)
+		if err != nil {
+			return err
+		}
+		records = append(records, r)
+	}
+
+	return printDiff(out, records[0], records[1])
+}
+
+func printDiff(out io.Writer, a, b scan.Record) error {
+	d := scan.Compare(a, b)
+
+	message := fmt.Sprintf("Scan %d (%s) -> scan %d (%s):\n",
+		a.ID, a.StartedAt.Format(timeFormat), b.ID, b.StartedAt.Format(timeFormat))
+
+	if d.Empty() {
+		message += fmt.Sprintln("No changes")
+	}
+	message += d.String()
+
+	_, err := fmt.Fprint(out, message)
+	return err
+}
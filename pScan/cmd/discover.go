@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"pScan/scan"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Find which hosts are up",
+	Long: `Checks which hosts of the list are up without scanning them.
+
+Every host gets an ICMP echo request, when the system permits sending
+one, and a TCP connection to each of the --ports. Any answer, even a
+refused connection, means the host is up. CIDR blocks and ranges are
+checked address by address. The echo request is IPv4 only, so with -6
+hosts are only checked over TCP.
+
+The probes count against --rate, --host-rate, --max-per-host and
+--jitter, like the connections of a scan.
+
+Use scan --skip-down to run the same check before scanning.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		opts := scan.Options{}
+
+		portSpecs, err := cmd.Flags().GetStringSlice("ports")
+		if err != nil {
+			return err
+		}
+
+		if opts.DiscoveryPorts, err = scan.ParsePorts(portSpecs); err != nil {
+			return err
+		}
+
+		if opts.Workers, err = cmd.Flags().GetInt("workers"); err != nil {
+			return err
+		}
+
+		if opts.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+			return err
+		}
+
+		if opts.Group, err = cmd.Flags().GetString("group"); err != nil {
+			return err
+		}
+
+		if opts.Tags, err = cmd.Flags().GetStringSlice("tag"); err != nil {
+			return err
+		}
+
+		if err = limitOptions(cmd, &opts); err != nil {
+			return err
+		}
+
+		if opts.Family, err = familyOption(cmd); err != nil {
+			return err
+		}
+
+		if opts.Resolver, err = resolverOption(cmd); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return discoverAction(ctx, os.Stdout, hostsFile, opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	defaultPorts := []string{}
+	for _, p := range scan.DiscoveryPorts {
+		defaultPorts = append(defaultPorts, strconv.Itoa(p))
+	}
+
+	discoverCmd.Flags().StringSliceP("ports", "p", defaultPorts, "TCP ports probed on each host")
+	discoverCmd.Flags().IntP("workers", "w", 100, "number of hosts checked concurrently")
+	discoverCmd.Flags().DurationP("timeout", "t", scan.DefaultTimeout,
+		"how long to wait for each host to answer")
+	discoverCmd.Flags().StringP("group", "g", "", "only check the hosts in this group")
+	discoverCmd.Flags().StringSlice("tag", nil, "only check the hosts with these tags")
+	addLimitFlags(discoverCmd)
+	addFamilyFlags(discoverCmd)
+	addDNSFlags(discoverCmd)
+}
+
+func discoverAction(ctx context.Context, out io.Writer, hostsFile string,
+	opts scan.Options) error {
+
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	status, err := scan.Discover(ctx, hl, opts)
+	if perr := printDiscover(out, status); perr != nil {
+		return perr
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: showing partial results", ErrInterrupted)
+	}
+
+	return nil
+}
+
+func printDiscover(out io.Writer, status []scan.HostStatus) error {
+	message := ""
+	up := 0
+
+	for _, s := range status {
+		switch {
+		case s.NotFound:
+			message += fmt.Sprintf("%s: Host not found\n", s.Host)
+		case s.Up:
+			up++
+			message += fmt.Sprintf("%s: up (%s, %s)\n", s.Host, s.Method,
+				s.Latency.Round(100*time.Microsecond))
+		default:
+			message += fmt.Sprintf("%s: down\n", s.Host)
+		}
+	}
+
+	message += fmt.Sprintf("\n%d of %d hosts up\n", up, len(status))
+
+	_, err := fmt.Fprint(out, message)
+	return err
+}
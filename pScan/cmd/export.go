@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"pScan/scan"
+
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the hosts list",
+	Long: `Writes the hosts list to the file, or to the standard output when no
+file is given.
+
+Formats are csv, which keeps the metadata of the hosts and can be read
+back with pScan hosts import, plain, one host per line as nmap -iL
+reads them, yaml and json.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return exportAction(os.Stdout, hostsFile, format)
+		}
+
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := exportAction(f, hostsFile, format); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("format", scan.FormatCSV,
+		"output format: csv, plain, yaml or json")
+}
+
+func exportAction(out io.Writer, hostsFile, format string) error {
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	return hl.Export(out, format)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"pScan/scan"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Manage the scan history",
+	Long: `Manages the history of scans run by pScan
+
+	Every scan is saved to the history file given by --history-file.
+	List saved scans with the list command and compare two of them
+	with pScan diff.`,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+// saveHistory appends the results of a scan to the history file and
+// returns them as saved. An empty file name disables the history, in
+// which case the record has no ID
+func saveHistory(historyFile string, results []scan.Results,
+	start, end time.Time) (scan.Record, error) {
+
+	r := scan.Record{
+		StartedAt:  start,
+		FinishedAt: end,
+		Results:    results,
+	}
+
+	if historyFile == "" {
+		return r, nil
+	}
+
+	h := &scan.History{}
+	if err := h.Load(historyFile); err != nil {
+		return r, err
+	}
+
+	return h.Append(historyFile, r)
+}
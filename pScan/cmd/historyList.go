@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pScan/scan"
+
+	"github.com/spf13/cobra"
+)
+
+// historyListCmd represents the history list command
+var historyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"l"},
+	Short:   "List saved scans",
+	Long: `Lists the scans saved in the history file, oldest first.
+
+Use the ID of a scan to compare it with another one using pScan diff.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+		return historyListAction(os.Stdout, historyFile)
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyListCmd)
+}
+
+func historyListAction(out io.Writer, historyFile string) error {
+	h := &scan.History{}
+
+	if err := h.Load(historyFile); err != nil {
+		return err
+	}
+
+	for _, r := range h.Records {
+		_, err := fmt.Fprintf(out, "%d\t%s\t%d hosts\t%d open ports\n",
+			r.ID, r.StartedAt.Format(timeFormat), len(r.Results), r.OpenPorts())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -15,7 +15,8 @@ var hostsCmd = &cobra.Command{
 	
 	Add hosts with the add command
 	Delete hosts with the delete command
-	List hosts with the list command`,
+	List hosts with the list command
+	Import and export hosts with the import and export commands`,
 }
 
 func init() {
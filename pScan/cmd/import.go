@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"pScan/scan"
+
+	"github.com/spf13/cobra"
+)
+
+// formatAuto makes import guess the format of each file
+const formatAuto = "auto"
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file1>...<filen>",
+	Short: "Import hosts from nmap, CSV or /etc/hosts files",
+	Long: `Adds the hosts found in the files to the list.
+
+Files can be nmap XML reports (nmap -oX), CSV files or /etc/hosts style
+files. With the default --format auto, .xml and .csv files and files
+starting with < are read as nmap reports or CSV, anything else as an
+/etc/hosts file.
+
+CSV files may start with a header naming the host, group, tags, ports
+and notes columns. Without one, the first column holds the hosts.
+Hosts found in nmap reports and /etc/hosts files are added by address,
+with their names as notes.
+
+Hosts already in the list, invalid entries, hosts nmap found down and
+loopback or multicast addresses are skipped and reported.`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		return importAction(os.Stdout, hostsFile, args, format)
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("format", formatAuto,
+		"format of the files: auto, nmap, csv or hosts")
+}
+
+func importAction(out io.Writer, hostsFile string, args []string, format string) error {
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	added, skipped := 0, 0
+	message := ""
+
+	for _, file := range args {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		f := format
+		if f == formatAuto {
+			f = scan.DetectFormat(file, data)
+		}
+
+		hosts, skip, err := hl.Import(bytes.NewReader(data), f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, h := range hosts {
+			message += fmt.Sprintln("Added host:", h)
+		}
+		for _, s := range skip {
+			message += fmt.Sprintf("Skipped %s: %s\n", s.Entry, s.Reason)
+		}
+
+		added += len(hosts)
+		skipped += len(skip)
+	}
+
+	message += fmt.Sprintf("Imported %d hosts, skipped %d\n", added, skipped)
+
+	if err := hl.Save(hostsFile); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprint(out, message)
+	return err
+}
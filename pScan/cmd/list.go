@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"pScan/scan"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -54,10 +55,33 @@ func listAction(out io.Writer, hostsFile string, args []string) error {
 	}
 
 	for _, h := range hl.Hosts {
-		if _, err := fmt.Fprintln(out, h); err != nil {
+		if _, err := fmt.Fprintln(out, h+hostInfo(hl.Info[h])); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// hostInfo formats the metadata of a host to show after its name
+func hostInfo(info scan.HostInfo) string {
+	s := ""
+
+	if info.Group != "" {
+		s += " group=" + info.Group
+	}
+	if len(info.Tags) > 0 {
+		s += " tags=" + strings.Join(info.Tags, ",")
+	}
+	if len(info.Ports) > 0 {
+		s += " ports=" + strings.Join(info.Ports, ",")
+	}
+	if info.Notes != "" {
+		s += fmt.Sprintf(" notes=%q", info.Notes)
+	}
+
+	if s == "" {
+		return s
+	}
+	return "\t" + strings.TrimSpace(s)
+}
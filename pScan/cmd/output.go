@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"pScan/scan"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidOutput = errors.New("Invalid output format")
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputXML   = "xml"
+)
+
+// scanReport is the representation of a scan in structured output
+type scanReport struct {
+	XMLName    xml.Name     `json:"-" xml:"scan"`
+	StartedAt  time.Time    `json:"started_at" xml:"started_at,attr"`
+	FinishedAt time.Time    `json:"finished_at" xml:"finished_at,attr"`
+	Hosts      []hostReport `json:"hosts" xml:"host"`
+}
+
+type hostReport struct {
+	Host      string       `json:"host" xml:"name,attr"`
+	Address   string       `json:"address,omitempty" xml:"address,attr,omitempty"`
+	Found     bool         `json:"found" xml:"found,attr"`
+	Down      bool         `json:"down,omitempty" xml:"down,attr,omitempty"`
+	Addresses []string     `json:"addresses,omitempty" xml:"address,omitempty"`
+	Names     []string     `json:"names,omitempty" xml:"dns_name,omitempty"`
+	Ports     []portReport `json:"ports,omitempty" xml:"port,omitempty"`
+}
+
+type portReport struct {
+	Port      int     `json:"port" xml:"number,attr"`
+	Protocol  string  `json:"protocol" xml:"protocol,attr"`
+	Service   string  `json:"service,omitempty" xml:"service,attr,omitempty"`
+	State     string  `json:"state" xml:"state,attr"`
+	LatencyMs float64 `json:"latency_ms" xml:"latency_ms,attr"`
+
+	Fingerprint *fingerprintReport `json:"fingerprint,omitempty" xml:"fingerprint,omitempty"`
+	TLS         *tlsReport         `json:"tls,omitempty" xml:"tls,omitempty"`
+}
+
+type fingerprintReport struct {
+	Service string `json:"service,omitempty" xml:"service,attr,omitempty"`
+	Version string `json:"version,omitempty" xml:"version,attr,omitempty"`
+	Banner  string `json:"banner,omitempty" xml:"banner,omitempty"`
+}
+
+type tlsReport struct {
+	Version   string    `json:"version" xml:"version,attr"`
+	ALPN      string    `json:"alpn,omitempty" xml:"alpn,attr,omitempty"`
+	Subject   string    `json:"subject" xml:"subject"`
+	Issuer    string    `json:"issuer" xml:"issuer"`
+	SANs      []string  `json:"sans,omitempty" xml:"san,omitempty"`
+	NotBefore time.Time `json:"not_before" xml:"not_before,attr"`
+	NotAfter  time.Time `json:"not_after" xml:"not_after,attr"`
+}
+
+func newScanReport(results []scan.Results, start, end time.Time) scanReport {
+	r := scanReport{
+		StartedAt:  start,
+		FinishedAt: end,
+		Hosts:      make([]hostReport, 0, len(results)),
+	}
+
+	for _, res := range results {
+		h := hostReport{
+			Host:      res.Host,
+			Address:   res.Address,
+			Found:     !res.NotFound,
+			Down:      res.Down,
+			Addresses: res.Addresses,
+			Names:     res.Names,
+		}
+
+		for _, p := range res.PortStates {
+			pr := portReport{
+				Port:      p.Port,
+				Protocol:  p.Proto,
+				Service:   scan.Service(p.Proto, p.Port),
+				State:     p.State.String(),
+				LatencyMs: float64(p.Latency.Microseconds()) / 1000,
+			}
+
+			if f := p.Fingerprint; f != nil {
+				pr.Fingerprint = &fingerprintReport{
+					Service: f.Service,
+					Version: f.Version,
+					Banner:  f.Banner,
+				}
+			}
+
+			if t := p.TLS; t != nil {
+				pr.TLS = &tlsReport{
+					Version:   t.Version,
+					ALPN:      t.ALPN,
+					Subject:   t.Subject,
+					Issuer:    t.Issuer,
+					SANs:      t.SANs,
+					NotBefore: t.NotBefore,
+					NotAfter:  t.NotAfter,
+				}
+			}
+
+			h.Ports = append(h.Ports, pr)
+		}
+
+		r.Hosts = append(r.Hosts, h)
+	}
+
+	return r
+}
+
+// validateOutput checks the value of the --output flag
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputCSV, outputXML:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q, use one of table, json, csv or xml",
+		ErrInvalidOutput, output)
+}
+
+// printReport writes the scan results in the given output format
+func printReport(out io.Writer, output string, r scanReport, results []scan.Results) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case outputXML:
+		if _, err := io.WriteString(out, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(out)
+		enc.Indent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(out)
+		return err
+	case outputCSV:
+		return printCSV(out, r)
+	case outputTable:
+		return printResults(out, results)
+	}
+
+	return validateOutput(output)
+}
+
+func printCSV(out io.Writer, r scanReport) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"host", "address", "found", "down", "port", "protocol", "service", "state",
+		"latency_ms", "detected_service", "version", "banner", "tls_version",
+		"cert_subject", "cert_not_after"})
+
+	for _, h := range r.Hosts {
+		found := strconv.FormatBool(h.Found)
+		down := strconv.FormatBool(h.Down)
+		if len(h.Ports) == 0 {
+			w.Write([]string{h.Host, h.Address, found, down, "", "", "", "", "", "", "", "",
+				"", "", ""})
+			continue
+		}
+
+		for _, p := range h.Ports {
+			f := fingerprintReport{}
+			if p.Fingerprint != nil {
+				f = *p.Fingerprint
+			}
+
+			tlsVersion, subject, notAfter := "", "", ""
+			if p.TLS != nil {
+				tlsVersion = p.TLS.Version
+				subject = p.TLS.Subject
+				notAfter = p.TLS.NotAfter.Format(time.RFC3339)
+			}
+
+			w.Write([]string{
+				h.Host,
+				h.Address,
+				found,
+				down,
+				strconv.Itoa(p.Port),
+				p.Protocol,
+				p.Service,
+				p.State,
+				strconv.FormatFloat(p.LatencyMs, 'f', 3, 64),
+				f.Service,
+				f.Version,
+				f.Banner,
+				tlsVersion,
+				subject,
+				notAfter,
+			})
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pScan/scan"
+	"strings"
+	"time"
+)
+
+// progressWidth is the number of characters in the progress bar
+const progressWidth = 30
+
+// progressBar draws the progress of a scan on a single line
+type progressBar struct {
+	out   io.Writer
+	every time.Duration
+	last  time.Time
+	drawn bool
+}
+
+// newProgressBar returns a bar drawing on out, or nil if out isn't a
+// terminal, so the bar doesn't end up in redirected output
+func newProgressBar(out *os.File) *progressBar {
+	info, err := out.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	return &progressBar{out: out, every: 100 * time.Millisecond}
+}
+
+// update redraws the bar for r, at most once every p.every and always
+// for the last port
+func (p *progressBar) update(r scan.PortResult) {
+	if r.Done < r.Total && time.Since(p.last) < p.every {
+		return
+	}
+	p.last = time.Now()
+	p.drawn = true
+
+	filled := progressWidth * r.Done / r.Total
+	fmt.Fprintf(p.out, "\r[%s%s] %d/%d ports %3d%%",
+		strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled),
+		r.Done, r.Total, 100*r.Done/r.Total)
+}
+
+// clear removes the bar so results can be printed
+func (p *progressBar) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
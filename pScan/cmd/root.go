@@ -19,10 +19,33 @@ var rootCmd = &cobra.Command{
 	pScan allows you to add, list, and delete hosts from the list.
 	
 	pScan executes a port scan on specified TCP ports. You can customize the
-	target ports using a command line flag.`,
+	target ports using a command line flag.
+
+	Flags can also be set in $HOME/.pScan.yaml, or the file given with
+	--config, and in PSCAN_ environment variables such as PSCAN_HOSTS_FILE.
+	Named profiles in the file group the options of a kind of scan:
+
+	  profile: quick
+	  hosts-file: lab.hosts
+	  profiles:
+	    quick:
+	      ports: [22, 80, 443]
+	      timeout: 300ms
+	    full:
+	      ports: [1-65535]
+	      workers: 500
+	      output: json
+
+	Flags on the command line win over the environment, which wins over
+	the profile chosen with --profile, which wins over the rest of the file.
+	Only scan, watch, discover and serve take all their options this way,
+	the other commands only take the global flags such as --hosts-file.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyConfig(cmd)
+	},
 	Version: "0.1",
 }
 
@@ -40,11 +63,15 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pScan.yaml)")
+	rootCmd.PersistentFlags().String("config", "", "config file (default is $HOME/.pScan.yaml)")
+	rootCmd.PersistentFlags().String("profile", "",
+		"profile of the config file to take options from")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.PersistentFlags().StringP("hosts-file", "f", "pScan.hosts", "pScan hosts file")
+	rootCmd.PersistentFlags().String("history-file", "pScan.history",
+		"file where scans are saved, empty to disable the history")
 
 	versionTemplate := `{{printf "%s: %s - version %s\n" .Name .Short .Version}}`
 	rootCmd.SetVersionTemplate(versionTemplate)
@@ -4,37 +4,63 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"pScan/scan"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	ErrInterrupted  = errors.New("Scan interrupted")
+	ErrInvalidLimit = errors.New("Invalid limit")
+)
+
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
-	Use:   "scan",
-	Short: "Run a port scan on the hosts",
+	Use:          "scan",
+	Short:        "Run a port scan on the hosts",
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostsFile, err := cmd.Flags().GetString("hosts-file")
 		if err != nil {
 			return err
 		}
 
-		ports, err := cmd.Flags().GetIntSlice("ports")
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		ports, opts, err := scanOptions(cmd)
 		if err != nil {
 			return err
 		}
 
-		return scanAction(os.Stdout, hostsFile, ports)
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return scanAction(ctx, os.Stdout, newProgressBar(os.Stderr), hostsFile,
+			historyFile, ports, opts, output)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
 
-	scanCmd.Flags().IntSliceP("ports", "p", []int{2, 80, 443}, "ports to scan")
+	addScanFlags(scanCmd)
+	scanCmd.Flags().StringP("output", "o", outputTable, "output format: table, json, csv or xml")
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -46,37 +72,307 @@ func init() {
 	// scanCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func scanAction(out io.Writer, hostsFile string, ports []int) error {
+// addScanFlags defines the flags that control how ports are scanned on
+// the commands that run scans
+func addScanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceP("ports", "p", []string{"2", "80", "443"},
+		"ports to scan: numbers, ranges (1-1024) or sets (top100, web, db). Prefix with ! to exclude")
+	cmd.Flags().IntP("workers", "w", 100, "number of ports scanned concurrently")
+	cmd.Flags().BoolP("udp", "u", false, "scan UDP ports instead of TCP")
+	cmd.Flags().DurationP("timeout", "t", scan.DefaultTimeout,
+		"how long to wait for each port before reporting it filtered")
+	cmd.Flags().BoolP("banners", "b", false,
+		"read banners from open TCP ports to identify their services")
+	cmd.Flags().Bool("tls", false,
+		"inspect the TLS certificates of open TCP ports, see pScan certs")
+	cmd.Flags().Bool("skip-down", false,
+		"check which hosts are up first and only scan those, see pScan discover")
+	cmd.Flags().StringP("group", "g", "", "only scan the hosts in this group")
+	cmd.Flags().StringSlice("tag", nil, "only scan the hosts with these tags")
+	cmd.Flags().Bool("randomize", false, "scan ports and hosts in random order")
+	cmd.Flags().Bool("reverse-dns", false, "look up the names of the hosts given as IP addresses")
+	addLimitFlags(cmd)
+	addFamilyFlags(cmd)
+	addDNSFlags(cmd)
+}
+
+// addLimitFlags defines the flags that limit how fast and how many
+// connections are made
+func addLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("rate", 0, "maximum connections per second for the whole scan, 0 for no limit")
+	cmd.Flags().Float64("host-rate", 0, "maximum connections per second to each address, 0 for no limit")
+	cmd.Flags().Int("max-per-host", 0,
+		"maximum connections open to each address at the same time, 0 for no limit")
+	cmd.Flags().Duration("jitter", 0, "wait a random time up to this long before each connection")
+}
+
+// addFamilyFlags defines the flags that limit scans to one address
+// family
+func addFamilyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("ipv4", "4", false, "only use the IPv4 addresses of the hosts")
+	cmd.Flags().BoolP("ipv6", "6", false, "only use the IPv6 addresses of the hosts")
+	cmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+}
+
+// addDNSFlags defines the flags that control how hosts are resolved
+func addDNSFlags(cmd *cobra.Command) {
+	cmd.Flags().String("dns-server", "",
+		"DNS server used instead of the system resolver, as an IP address with an optional port")
+	cmd.Flags().Duration("dns-timeout", scan.DefaultDNSTimeout, "how long to wait for each DNS lookup")
+	cmd.Flags().Duration("dns-cache", time.Minute,
+		"how long resolved names are reused, across the scans of watch as well, 0 for ever")
+}
+
+// resolverOption reads the flags defined by addDNSFlags
+func resolverOption(cmd *cobra.Command) (*scan.Resolver, error) {
+	server, err := cmd.Flags().GetString("dns-server")
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := cmd.Flags().GetDuration("dns-timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := cmd.Flags().GetDuration("dns-cache")
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout < 0 || ttl < 0 {
+		return nil, fmt.Errorf("%w: --dns-timeout and --dns-cache can't be negative",
+			ErrInvalidLimit)
+	}
+
+	return scan.NewResolver(server, timeout, ttl)
+}
+
+// familyOption reads the flags defined by addFamilyFlags
+func familyOption(cmd *cobra.Command) (string, error) {
+	ipv4, err := cmd.Flags().GetBool("ipv4")
+	if err != nil {
+		return "", err
+	}
+
+	ipv6, err := cmd.Flags().GetBool("ipv6")
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case ipv4:
+		return scan.IPv4, nil
+	case ipv6:
+		return scan.IPv6, nil
+	}
+	return "", nil
+}
+
+// scanOptions reads the flags defined by addScanFlags
+func scanOptions(cmd *cobra.Command) ([]int, scan.Options, error) {
+	opts := scan.Options{}
+
+	portSpecs, err := cmd.Flags().GetStringSlice("ports")
+	if err != nil {
+		return nil, opts, err
+	}
+
+	ports, err := scan.ParsePorts(portSpecs)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Workers, err = cmd.Flags().GetInt("workers"); err != nil {
+		return nil, opts, err
+	}
+
+	udp, err := cmd.Flags().GetBool("udp")
+	if err != nil {
+		return nil, opts, err
+	}
+
+	opts.Proto = scan.TCP
+	if udp {
+		opts.Proto = scan.UDP
+	}
+
+	if opts.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Banners, err = cmd.Flags().GetBool("banners"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.TLS, err = cmd.Flags().GetBool("tls"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.SkipDown, err = cmd.Flags().GetBool("skip-down"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Group, err = cmd.Flags().GetString("group"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Tags, err = cmd.Flags().GetStringSlice("tag"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Family, err = familyOption(cmd); err != nil {
+		return nil, opts, err
+	}
+
+	if err = limitOptions(cmd, &opts); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Randomize, err = cmd.Flags().GetBool("randomize"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.ReverseDNS, err = cmd.Flags().GetBool("reverse-dns"); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.Resolver, err = resolverOption(cmd); err != nil {
+		return nil, opts, err
+	}
+
+	return ports, opts, nil
+}
+
+// limitOptions sets the connection limits of opts from the flags
+// defined by addLimitFlags
+func limitOptions(cmd *cobra.Command, opts *scan.Options) error {
+	var err error
+
+	if opts.Rate, err = cmd.Flags().GetFloat64("rate"); err != nil {
+		return err
+	}
+
+	if opts.HostRate, err = cmd.Flags().GetFloat64("host-rate"); err != nil {
+		return err
+	}
+
+	if opts.MaxPerHost, err = cmd.Flags().GetInt("max-per-host"); err != nil {
+		return err
+	}
+
+	if opts.Jitter, err = cmd.Flags().GetDuration("jitter"); err != nil {
+		return err
+	}
+
+	if opts.Rate < 0 || opts.HostRate < 0 || opts.MaxPerHost < 0 || opts.Jitter < 0 {
+		return fmt.Errorf("%w: rates, --max-per-host and --jitter can't be negative",
+			ErrInvalidLimit)
+	}
+
+	return nil
+}
+
+// scanAction scans the hosts and prints the results. Progress is drawn
+// on bar while the scan runs, unless bar is nil. If ctx is canceled the
+// partial results are printed but not saved to the history
+func scanAction(ctx context.Context, out io.Writer, bar *progressBar,
+	hostsFile, historyFile string, ports []int, opts scan.Options,
+	output string) error {
+
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+
 	hl := &scan.HostsList{}
 
 	if err := hl.Load(hostsFile); err != nil {
 		return err
 	}
 
-	results := scan.Run(hl, ports)
-	return printResults(out, results)
+	if bar != nil {
+		opts.OnPort = bar.update
+	}
+
+	start := time.Now()
+	results, scanErr := scan.RunContext(ctx, hl, ports, opts)
+	end := time.Now()
+
+	if bar != nil {
+		bar.clear()
+	}
+
+	if scanErr == nil {
+		if _, err := saveHistory(historyFile, results, start, end); err != nil {
+			return err
+		}
+	}
+
+	report := newScanReport(results, start, end)
+
+	if err := printReport(out, output, report, results); err != nil {
+		return err
+	}
+
+	if scanErr != nil {
+		return fmt.Errorf("%w: showing partial results", ErrInterrupted)
+	}
+
+	return nil
 }
 
 func printResults(out io.Writer, results []scan.Results) error {
 	message := ""
 
 	for _, r := range results {
-		message += fmt.Sprintf("%s:", r.Host)
+		message += r.Name()
+		if len(r.Names) > 0 {
+			message += fmt.Sprintf(" [%s]", strings.Join(r.Names, ", "))
+		}
+		message += ":"
 
 		if r.NotFound {
 			message += fmt.Sprintf(" Host not found\n\n")
 			continue
 		}
 
+		if r.Down {
+			message += fmt.Sprintf(" Host down\n\n")
+			continue
+		}
+
 		message += fmt.Sprintln()
 
 		for _, p := range r.PortStates {
-			message += fmt.Sprintf("\t%d: %s\n", p.Port, p.Open)
+			message += fmt.Sprintf("\t%s: %s", portLabel(p), p.State)
+			if p.Fingerprint != nil {
+				message += fmt.Sprintf("  %s", p.Fingerprint)
+			}
+			message += fmt.Sprintln()
+
+			if t := p.TLS; t != nil {
+				message += fmt.Sprintf("\t\t%s, %s, expires %s\n", t.Version, t.Subject,
+					t.NotAfter.Format(timeFormat))
+			}
 		}
 
 		message += fmt.Sprintln()
 	}
 
-	_, err := fmt.Fprintf(out, message)
+	_, err := fmt.Fprint(out, message)
 	return err
 }
+
+// portLabel shows the port along with its service name, if known. UDP
+// ports are marked so they aren't mistaken for TCP ones
+func portLabel(p scan.PortState) string {
+	label := fmt.Sprintf("%d", p.Port)
+	if p.Proto == scan.UDP {
+		label += "/udp"
+	}
+
+	if s := scan.Service(p.Proto, p.Port); s != "" {
+		return fmt.Sprintf("%s (%s)", label, s)
+	}
+	return label
+}
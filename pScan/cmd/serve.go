@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"pScan/server"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout is how long serve waits for requests in flight when
+// it stops
+const shutdownTimeout = 5 * time.Second
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run pScan as an HTTP service",
+	Long: `Serves a JSON API to manage the hosts list and run scans from other
+tools.
+
+  GET    /hosts               list the hosts
+  POST   /hosts               add a host: {"name": "host1", "group": "web"}
+  GET    /hosts/<name>        show a host
+  DELETE /hosts/<name>        delete a host
+  GET    /jobs                list the scan jobs
+  POST   /jobs                submit a scan: {"ports": ["22", "80-90"]}
+  GET    /jobs/<id>           show the status of a job
+  GET    /jobs/<id>/results   fetch the results of a finished job
+  DELETE /jobs/<id>           cancel a job
+
+Jobs accept the options of pScan scan: ports, udp, workers, timeout,
+banners, tls, skip_down, family, group, tags, rate, host_rate,
+max_per_host, randomize and jitter. They run one at a time
+unless --concurrency says otherwise. Finished jobs are kept in
+--jobs-file and their scans saved to the history.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := server.Config{}
+		var err error
+
+		if cfg.HostsFile, err = cmd.Flags().GetString("hosts-file"); err != nil {
+			return err
+		}
+
+		if cfg.HistoryFile, err = cmd.Flags().GetString("history-file"); err != nil {
+			return err
+		}
+
+		if cfg.JobsFile, err = cmd.Flags().GetString("jobs-file"); err != nil {
+			return err
+		}
+
+		if cfg.Concurrency, err = cmd.Flags().GetInt("concurrency"); err != nil {
+			return err
+		}
+
+		addr, err := cmd.Flags().GetString("addr")
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return serveAction(ctx, os.Stdout, addr, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringP("addr", "a", "localhost:8080", "address to listen on")
+	serveCmd.Flags().String("jobs-file", "pScan.jobs",
+		"file where finished jobs are kept, empty to keep them in memory")
+	serveCmd.Flags().IntP("concurrency", "c", 1, "number of jobs run at the same time")
+}
+
+// serveAction serves the API on addr until ctx is canceled
+func serveAction(ctx context.Context, out io.Writer, addr string,
+	cfg server.Config) error {
+
+	s, err := server.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	// jobs stop with the server, whatever stops it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobsDone := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(jobsDone)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	fmt.Fprintf(out, "Serving the pScan API on http://%s\n", ln.Addr())
+
+	select {
+	case err = <-serveErr:
+		cancel()
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err = srv.Shutdown(shutdownCtx)
+	}
+
+	<-jobsDone
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
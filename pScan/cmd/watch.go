@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"pScan/alert"
+	"pScan/scan"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrInvalidAlert    = errors.New("Invalid alert")
+	ErrInvalidInterval = errors.New("Invalid interval")
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Scan the hosts repeatedly and alert on changes",
+	Long: `Scans the hosts list every --interval and compares each scan with the
+previous one. Hosts that appear or disappear and ports that open or
+close raise an alert on every sink given with --alert:
+
+  stdout        print the changes
+  webhook=URL   post the changes as JSON to URL
+  desktop       show a desktop notification
+
+Every scan is saved to the history. Press Ctrl+C to stop.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile, err := cmd.Flags().GetString("hosts-file")
+		if err != nil {
+			return err
+		}
+
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			return err
+		}
+
+		ports, opts, err := scanOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			return err
+		}
+		// without a pause watch would rescan, and grow the history, in a
+		// tight loop
+		if interval <= 0 {
+			return fmt.Errorf("%w: --interval must be greater than zero, got %s",
+				ErrInvalidInterval, interval)
+		}
+
+		alertSpecs, err := cmd.Flags().GetStringSlice("alert")
+		if err != nil {
+			return err
+		}
+
+		sinks, err := newSinks(os.Stdout, alertSpecs)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return watchAction(ctx, os.Stdout, hostsFile, historyFile, ports, opts,
+			interval, sinks)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addScanFlags(watchCmd)
+	watchCmd.Flags().DurationP("interval", "i", 10*time.Minute, "time between scans")
+	watchCmd.Flags().StringSliceP("alert", "a", []string{"stdout"},
+		"where to send alerts: stdout, webhook=URL or desktop")
+}
+
+// newSinks creates the alert sinks given with --alert
+func newSinks(out io.Writer, specs []string) ([]alert.Sink, error) {
+	sinks := []alert.Sink{}
+
+	for _, spec := range specs {
+		name, arg, _ := strings.Cut(strings.TrimSpace(spec), "=")
+
+		switch name {
+		case "stdout":
+			sinks = append(sinks, &alert.Writer{Out: out})
+		case "desktop":
+			sinks = append(sinks, alert.Desktop{})
+		case "webhook":
+			if arg == "" {
+				return nil, fmt.Errorf("%w: webhook needs a URL, as in webhook=https://...",
+					ErrInvalidAlert)
+			}
+			sinks = append(sinks, alert.NewWebhook(arg))
+		default:
+			return nil, fmt.Errorf("%w: %q, use stdout, webhook=URL or desktop",
+				ErrInvalidAlert, spec)
+		}
+	}
+
+	return sinks, nil
+}
+
+// watchAction scans the hosts every interval until ctx is canceled,
+// sending an alert to every sink when a scan differs from the one
+// before. A scan in progress when ctx is canceled is discarded
+func watchAction(ctx context.Context, out io.Writer, hostsFile, historyFile string,
+	ports []int, opts scan.Options, interval time.Duration, sinks []alert.Sink) error {
+
+	var prev *scan.Record
+
+	fmt.Fprintf(out, "Watching %s every %s\n", hostsFile, interval)
+
+	for {
+		hl := &scan.HostsList{}
+		if err := hl.Load(hostsFile); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		results, err := scan.RunContext(ctx, hl, ports, opts)
+		if err != nil {
+			// a partial scan would raise false alerts
+			fmt.Fprintln(out, "Stopped watching")
+			return nil
+		}
+
+		r, err := saveHistory(historyFile, results, start, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if prev != nil {
+			if d := scan.Compare(*prev, r); !d.Empty() {
+				sendAlert(out, sinks, alert.Alert{
+					Time:     r.FinishedAt,
+					Previous: *prev,
+					Current:  r,
+					Diff:     d,
+				})
+			}
+		}
+		prev = &r
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(out, "Stopped watching")
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sendAlert delivers a to every sink. A failing sink doesn't stop the
+// watch, it is only reported
+func sendAlert(out io.Writer, sinks []alert.Sink, a alert.Alert) {
+	for _, s := range sinks {
+		if err := s.Send(a); err != nil {
+			fmt.Fprintf(out, "Error sending alert: %s\n", err)
+		}
+	}
+}
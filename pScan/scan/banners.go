@@ -0,0 +1,233 @@
+package scan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxBanner limits the length of the banner kept for a port
+const maxBanner = 80
+
+// Fingerprint describes the service found on an open port
+type Fingerprint struct {
+	// Service is the protocol spoken on the port, such as ssh or http.
+	// It is empty when the banner isn't recognized
+	Service string `json:"service,omitempty"`
+	// Version is the product and version announced by the service
+	Version string `json:"version,omitempty"`
+	// Banner is the first line sent by the service
+	Banner string `json:"banner,omitempty"`
+}
+
+// String shows the service and version, or the raw banner if the
+// service wasn't identified
+func (f *Fingerprint) String() string {
+	if f.Service == "" {
+		return f.Banner
+	}
+	return strings.TrimSpace(f.Service + " " + f.Version)
+}
+
+const (
+	probeHTTP  = "http"
+	probeRedis = "redis"
+)
+
+// probePorts lists the ports of services that wait for the client to
+// speak first, along with the probe to send them
+var probePorts = map[int]string{
+	80:   probeHTTP,
+	8000: probeHTTP,
+	8008: probeHTTP,
+	8080: probeHTTP,
+	8081: probeHTTP,
+	8888: probeHTTP,
+	6379: probeRedis,
+}
+
+// grabBanner identifies the service on an open connection. Services
+// known to wait for the client get their probe right away. Everything
+// else is given timeout to send a greeting, as SSH, SMTP and FTP do,
+// and receives an HTTP HEAD request if it stays silent
+func grabBanner(conn net.Conn, host string, port int, timeout time.Duration) *Fingerprint {
+	conn.SetDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+
+	switch probePorts[port] {
+	case probeHTTP:
+		return probeHTTPService(conn, r, host)
+	case probeRedis:
+		return probeRedisService(conn, r)
+	}
+
+	line, err := readLine(r)
+	if line != "" {
+		return parseGreeting(conn, line)
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return nil
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	return probeHTTPService(conn, r, host)
+}
+
+// parseGreeting identifies the services that announce themselves as
+// soon as the connection is open
+func parseGreeting(conn net.Conn, line string) *Fingerprint {
+	f := &Fingerprint{Banner: line}
+
+	switch {
+	case strings.HasPrefix(line, "SSH-"):
+		// SSH-2.0-OpenSSH_8.9p1 Ubuntu-3
+		if parts := strings.SplitN(line, "-", 3); len(parts) == 3 {
+			f.Service = "ssh"
+			f.Version = parts[2]
+		}
+	case strings.HasPrefix(line, "220"):
+		// 220 mail.example.com ESMTP Postfix (Ubuntu)
+		switch upper := strings.ToUpper(line); {
+		case strings.Contains(upper, "SMTP"):
+			f.Service = "smtp"
+		case strings.Contains(upper, "FTP"):
+			f.Service = "ftp"
+		default:
+			return f
+		}
+		if fields := strings.SplitN(line, " ", 3); len(fields) == 3 {
+			f.Version = fields[2]
+		}
+		// leave politely so the server doesn't log an aborted session
+		io.WriteString(conn, "QUIT\r\n")
+	case strings.HasPrefix(line, "+OK"):
+		f.Service = "pop3"
+		f.Version = strings.TrimSpace(strings.TrimPrefix(line, "+OK"))
+	case strings.HasPrefix(line, "* OK"):
+		f.Service = "imap"
+		f.Version = strings.TrimSpace(strings.TrimPrefix(line, "* OK"))
+	}
+
+	return f
+}
+
+// probeHTTPService sends a HEAD request and reads the Server header of
+// the response
+func probeHTTPService(conn net.Conn, r *bufio.Reader, host string) *Fingerprint {
+	_, err := fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\nUser-Agent: pScan\r\n\r\n", host)
+	if err != nil {
+		return nil
+	}
+
+	status, _ := readLine(r)
+	if status == "" {
+		return nil
+	}
+
+	f := &Fingerprint{Banner: status}
+	if !strings.HasPrefix(status, "HTTP/") {
+		return f
+	}
+
+	f.Service = "http"
+	for {
+		line, err := readLine(r)
+		if line == "" || err != nil {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Server") {
+			f.Version = sanitize(value)
+			break
+		}
+	}
+
+	return f
+}
+
+// probeRedisService sends a PING and, if the server doesn't require
+// authentication, reads its version from INFO
+func probeRedisService(conn net.Conn, r *bufio.Reader) *Fingerprint {
+	if _, err := io.WriteString(conn, "PING\r\n"); err != nil {
+		return nil
+	}
+
+	line, _ := readLine(r)
+	if line == "" {
+		return nil
+	}
+
+	f := &Fingerprint{Banner: line}
+	switch {
+	case line == "+PONG":
+		f.Service = "redis"
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-DENIED"):
+		f.Service = "redis"
+		return f
+	default:
+		return f
+	}
+
+	if _, err := io.WriteString(conn, "INFO server\r\n"); err != nil {
+		return f
+	}
+
+	// the reply is a bulk string: $<length> followed by the data
+	size, _ := readLine(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(size, "$"))
+	if err != nil || n <= 0 {
+		return f
+	}
+
+	info := make([]byte, n)
+	if _, err := io.ReadFull(r, info); err != nil {
+		return f
+	}
+
+	for _, l := range strings.Split(string(info), "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "redis_version:") {
+			f.Version = strings.TrimPrefix(l, "redis_version:")
+			break
+		}
+	}
+
+	return f
+}
+
+// readLine reads a single line, returning what was received so far if
+// the connection times out or closes first
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	return sanitize(line), err
+}
+
+// sanitize trims s and removes control characters so banners are safe
+// to print
+func sanitize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	if len(s) <= maxBanner {
+		return s
+	}
+
+	// cut before the rune that crosses the limit, not in the middle of it
+	n := maxBanner
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,170 @@
+package scan_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"pScan/scan"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve starts a TCP server on a local port and calls handle for every
+// connection it accepts
+func serve(t *testing.T, handle func(conn net.Conn)) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// greet sends msg as soon as the client connects, like SSH or SMTP do
+func greet(msg string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		io.WriteString(conn, msg)
+		io.Copy(io.Discard, conn)
+	}
+}
+
+func fakeRedis(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch strings.TrimSpace(cmd) {
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "INFO server":
+			info := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		}
+	}
+}
+
+func TestBanners(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", "nginx/1.25.3")
+		}))
+	defer web.Close()
+
+	redisPort := serve(t, fakeRedis)
+	defer scan.SetProbePort(redisPort, "redis")()
+
+	testCases := []struct {
+		name   string
+		port   int
+		expect *scan.Fingerprint
+	}{
+		{"SSH", serve(t, greet("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3\r\n")),
+			&scan.Fingerprint{Service: "ssh", Version: "OpenSSH_8.9p1 Ubuntu-3",
+				Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"}},
+		{"SMTP", serve(t, greet("220 mail.example.com ESMTP Postfix\r\n")),
+			&scan.Fingerprint{Service: "smtp", Version: "ESMTP Postfix",
+				Banner: "220 mail.example.com ESMTP Postfix"}},
+		{"Unknown", serve(t, greet("hello\x07 there\n")),
+			&scan.Fingerprint{Banner: "hello there"}},
+		{"LongUTF8", serve(t, greet("x"+strings.Repeat("é", 40)+"\n")),
+			&scan.Fingerprint{Banner: "x" + strings.Repeat("é", 39)}},
+		{"HTTP", web.Listener.Addr().(*net.TCPAddr).Port,
+			&scan.Fingerprint{Service: "http", Version: "nginx/1.25.3",
+				Banner: "HTTP/1.0 200 OK"}},
+		{"Redis", redisPort,
+			&scan.Fingerprint{Service: "redis", Version: "7.2.4", Banner: "+PONG"}},
+		{"Silent", serve(t, func(conn net.Conn) { io.Copy(io.Discard, conn) }), nil},
+	}
+
+	ports := []int{}
+	for _, tc := range testCases {
+		ports = append(ports, tc.port)
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	res := scan.Run(hl, ports, scan.Options{
+		Workers: 10,
+		Timeout: 200 * time.Millisecond,
+		Banners: true,
+	})
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := res[0].PortStates[i]
+			if p.State != scan.StateOpen {
+				t.Fatalf("Expected port %d to be open, got %s instead\n", p.Port, p.State)
+			}
+
+			if tc.expect == nil {
+				if p.Fingerprint != nil {
+					t.Errorf("Expected no fingerprint, got %+v instead\n", p.Fingerprint)
+				}
+				return
+			}
+
+			if p.Fingerprint == nil {
+				t.Fatalf("Expected fingerprint %+v, got nil instead\n", tc.expect)
+			}
+
+			if *p.Fingerprint != *tc.expect {
+				t.Errorf("Expected fingerprint %+v, got %+v instead\n",
+					tc.expect, p.Fingerprint)
+			}
+		})
+	}
+}
+
+func TestBannersDisabled(t *testing.T) {
+	port := serve(t, greet("SSH-2.0-OpenSSH_8.9p1\r\n"))
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	res := scan.Run(hl, []int{port}, scan.Options{Workers: 1})
+
+	if res[0].PortStates[0].Fingerprint != nil {
+		t.Errorf("Expected no fingerprint without banners, got %+v instead\n",
+			res[0].PortStates[0].Fingerprint)
+	}
+}
+
+func TestFingerprintString(t *testing.T) {
+	testCases := []struct {
+		f      scan.Fingerprint
+		expect string
+	}{
+		{scan.Fingerprint{Service: "http", Version: "nginx/1.25.3"}, "http nginx/1.25.3"},
+		{scan.Fingerprint{Service: "redis"}, "redis"},
+		{scan.Fingerprint{Banner: "hello"}, "hello"},
+	}
+
+	for _, tc := range testCases {
+		if s := tc.f.String(); s != tc.expect {
+			t.Errorf("Expected %q, got %q instead\n", tc.expect, s)
+		}
+	}
+}
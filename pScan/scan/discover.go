@@ -0,0 +1,161 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// DiscoveryPorts are the TCP ports probed to find out whether a host is
+// up when no other ports are given
+var DiscoveryPorts = []int{80, 443, 22, 445, 3389}
+
+// HostStatus tells whether a host answered the discovery probes
+type HostStatus struct {
+	Host      string
+	NotFound  bool
+	Addresses []string
+	Up        bool
+	// Method is the probe that got an answer, such as icmp or tcp/443
+	Method  string
+	Latency time.Duration
+}
+
+// Discover checks which hosts of the list are up, using up to
+// opts.Workers concurrent hosts. Hosts are resolved with opts.Resolver
+// and each of their addresses, until one answers, gets an ICMP echo
+// request, when the system permits sending one, and a TCP connection to
+// every port in opts.DiscoveryPorts. Any answer, including a refused
+// connection, means the host is up. Only hosts matching opts.Group and
+// opts.Tags are checked. If ctx is canceled Discover returns the hosts
+// checked so far along with the context error
+func Discover(ctx context.Context, hl *HostsList, opts Options) ([]HostStatus, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = &Resolver{}
+	}
+
+	hosts := []string{}
+	for _, t := range targets(hl, nil, opts) {
+		t.each(func(host string) {
+			hosts = append(hosts, host)
+		})
+	}
+
+	status := make([]HostStatus, len(hosts))
+	checked := make([]bool, len(hosts))
+
+	rate := newLimiter(opts.Rate)
+
+	parallel(ctx, len(hosts), opts.Workers, func(i int) {
+		s := HostStatus{Host: hosts[i]}
+
+		addrs, err := opts.Resolver.LookupHost(ctx, s.Host)
+		if err == nil {
+			addrs = filterFamily(addrs, opts.Family)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil || len(addrs) == 0 {
+			s.NotFound = true
+		} else {
+			s.Addresses = addrs
+			// probing the name would resolve it again, through the system
+			// resolver and for every family
+			for _, addr := range addrs {
+				s.Up, s.Method, s.Latency = probeHost(ctx, addr, opts, newHostLimits(opts), rate)
+				if s.Up || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		status[i] = s
+		checked[i] = true
+	})
+
+	if ctx.Err() == nil {
+		return status, nil
+	}
+
+	partial := []HostStatus{}
+	for i, s := range status {
+		if checked[i] {
+			partial = append(partial, s)
+		}
+	}
+
+	return partial, ctx.Err()
+}
+
+// probeHost runs the discovery probes on addr at the same time, as far
+// as the limits of the scan and of the address allow, and returns as
+// soon as one of them gets an answer
+func probeHost(ctx context.Context, addr string, opts Options, limits *hostLimits,
+	rate *limiter) (bool, string, time.Duration) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ports := opts.DiscoveryPorts
+	if len(ports) == 0 {
+		ports = DiscoveryPorts
+	}
+
+	type answer struct {
+		up     bool
+		method string
+	}
+
+	answers := make(chan answer, len(ports)+1)
+	start := time.Now()
+
+	// the echo probe only speaks ICMPv4
+	ip, err := netip.ParseAddr(addr)
+	v4 := err == nil && ip.Unmap().Is4()
+
+	go func() {
+		up := false
+		if v4 {
+			limited(ctx, limits, rate, opts, func() {
+				up = pingHost(ctx, addr, opts.Timeout)
+			})
+		}
+		answers <- answer{up, "icmp"}
+	}()
+
+	for _, port := range ports {
+		go func(port int) {
+			up := false
+			limited(ctx, limits, rate, opts, func() {
+				address := net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+				conn, err := dialTimeout(ctx, opts.network(TCP), address, opts.Timeout)
+				if err == nil {
+					conn.Close()
+				}
+				// a reset proves the host is there, even if the port is closed
+				up = err == nil || errors.Is(err, syscall.ECONNREFUSED)
+			})
+			answers <- answer{up, fmt.Sprintf("tcp/%d", port)}
+		}(port)
+	}
+
+	for i := 0; i < len(ports)+1; i++ {
+		if a := <-answers; a.up {
+			return true, a.method, time.Since(start)
+		}
+	}
+
+	return false, "", time.Since(start)
+}
@@ -0,0 +1,160 @@
+package scan_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"pScan/scan"
+	"testing"
+	"time"
+)
+
+// unreachable is blackholed by the tests so it never answers
+const unreachable = "127.0.0.2"
+
+func TestDiscover(t *testing.T) {
+	defer scan.DisablePing()()
+	defer scan.Blackhole(unreachable)()
+
+	ports := listenPorts(t, 2)
+	open, closed := ports[0], ports[1]
+
+	testCases := []struct {
+		name         string
+		host         string
+		probe        int
+		expectUp     bool
+		expectMethod string
+	}{
+		{"Open", "127.0.0.1", open, true, fmt.Sprintf("tcp/%d", open)},
+		{"Refused", "127.0.0.1", closed, true, fmt.Sprintf("tcp/%d", closed)},
+		{"NoAnswer", unreachable, open, false, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := &scan.HostsList{}
+			hl.Add(tc.host)
+
+			status, err := scan.Discover(context.Background(), hl, scan.Options{
+				Workers:        1,
+				Timeout:        200 * time.Millisecond,
+				DiscoveryPorts: []int{tc.probe},
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if len(status) != 1 {
+				t.Fatalf("Expected 1 host, got %d instead\n", len(status))
+			}
+
+			s := status[0]
+			if s.Up != tc.expectUp || s.Method != tc.expectMethod {
+				t.Errorf("Expected up %t by %q, got %t by %q instead\n",
+					tc.expectUp, tc.expectMethod, s.Up, s.Method)
+			}
+		})
+	}
+}
+
+func TestDiscoverResolvedAddresses(t *testing.T) {
+	defer scan.DisablePing()()
+	defer scan.Blackhole(unreachable)()
+
+	open := listenPorts(t, 1)[0]
+
+	// only the resolver of the scan knows the name
+	defer scan.SetLookup(map[string][]string{
+		"db.lab.test": {unreachable, "127.0.0.1"},
+	})()
+
+	hl := &scan.HostsList{}
+	hl.Add("db.lab.test")
+
+	status, err := scan.Discover(context.Background(), hl, scan.Options{
+		Workers:        1,
+		Timeout:        100 * time.Millisecond,
+		DiscoveryPorts: []int{open},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	s := status[0]
+	if s.NotFound || !s.Up || s.Method != fmt.Sprintf("tcp/%d", open) {
+		t.Errorf("Expected host up by tcp/%d, got %+v instead\n", open, s)
+	}
+	if len(s.Addresses) != 2 {
+		t.Errorf("Expected 2 addresses, got %q instead\n", s.Addresses)
+	}
+}
+
+func TestDiscoverNotFound(t *testing.T) {
+	hl := &scan.HostsList{}
+	hl.Add("389.389.389.389")
+
+	status, err := scan.Discover(context.Background(), hl, scan.Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	if !status[0].NotFound || status[0].Up {
+		t.Errorf("Expected host not found, got %+v instead\n", status[0])
+	}
+}
+
+func TestRunSkipDown(t *testing.T) {
+	defer scan.DisablePing()()
+	defer scan.Blackhole(unreachable)()
+
+	ports := listenPorts(t, 2)
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+	hl.Add(unreachable)
+
+	res := scan.Run(hl, ports, scan.Options{
+		Workers:        10,
+		Timeout:        200 * time.Millisecond,
+		SkipDown:       true,
+		DiscoveryPorts: ports[:1],
+	})
+
+	if len(res) != 2 {
+		t.Fatalf("Expected 2 results, got %d instead\n", len(res))
+	}
+
+	if res[0].Down || len(res[0].PortStates) != len(ports) {
+		t.Errorf("Expected 127.0.0.1 to be scanned, got %+v instead\n", res[0])
+	}
+
+	if !res[1].Down || len(res[1].PortStates) != 0 {
+		t.Errorf("Expected %s to be skipped, got %+v instead\n", unreachable, res[1])
+	}
+}
+
+func TestEchoRequest(t *testing.T) {
+	msg := scan.EchoRequest(0x1234, 7)
+
+	if msg[0] != 8 || msg[1] != 0 {
+		t.Errorf("Expected echo request type 8 code 0, got %d %d\n", msg[0], msg[1])
+	}
+
+	// a message with a valid checksum sums to zero
+	if c := scan.Checksum(msg); c != 0 {
+		t.Errorf("Expected checksum to verify, got %#x instead\n", c)
+	}
+}
+
+func TestPing(t *testing.T) {
+	conn, err := net.Dial("ip4:icmp", "127.0.0.1")
+	if err != nil {
+		t.Skipf("ICMP not permitted: %s", err)
+	}
+	conn.Close()
+
+	if !scan.Ping(context.Background(), "127.0.0.1", time.Second) {
+		t.Error("Expected 127.0.0.1 to answer ping")
+	}
+}
@@ -0,0 +1,161 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidDNSServer = errors.New("Invalid DNS server")
+
+// DefaultDNSTimeout is how long a Resolver waits for each lookup when
+// no timeout is given
+const DefaultDNSTimeout = 5 * time.Second
+
+// lookupHost and lookupAddr run the lookups of a Resolver. Tests
+// replace them to simulate hosts with several addresses
+var (
+	lookupHost = func(ctx context.Context, r *net.Resolver, host string) ([]string, error) {
+		return r.LookupHost(ctx, host)
+	}
+	lookupAddr = func(ctx context.Context, r *net.Resolver, addr string) ([]string, error) {
+		return r.LookupAddr(ctx, addr)
+	}
+)
+
+// Resolver resolves the hosts to scan and, for reverse lookups, the
+// names of their addresses. Answers are cached, so every name is looked
+// up once however many times the scans sharing the Resolver need it.
+// The zero value queries the system resolver with DefaultDNSTimeout and
+// caches answers for as long as it is used
+type Resolver struct {
+	server  string
+	timeout time.Duration
+	ttl     time.Duration
+
+	once     sync.Once
+	resolver *net.Resolver
+
+	mu    sync.Mutex
+	cache map[string]cachedLookup
+}
+
+type cachedLookup struct {
+	names   []string
+	err     error
+	expires time.Time
+}
+
+// NewResolver returns a Resolver that queries server instead of the
+// system resolver, unless it is empty. server is an IP address,
+// optionally followed by a port, which defaults to 53. Each lookup waits
+// up to timeout, or DefaultDNSTimeout if it is zero. Answers are kept
+// for ttl, or for the life of the Resolver if it is zero
+func NewResolver(server string, timeout, ttl time.Duration) (*Resolver, error) {
+	r := &Resolver{timeout: timeout, ttl: ttl}
+
+	if server == "" {
+		return r, nil
+	}
+
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, "53"
+	}
+
+	if _, err := netip.ParseAddr(host); err != nil {
+		return nil, fmt.Errorf("%w: %s: not an IP address", ErrInvalidDNSServer, server)
+	}
+	r.server = net.JoinHostPort(host, port)
+
+	return r, nil
+}
+
+// netResolver returns the resolver the lookups go to
+func (r *Resolver) netResolver() *net.Resolver {
+	r.once.Do(func() {
+		if r.server == "" {
+			r.resolver = net.DefaultResolver
+			return
+		}
+
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{}
+				return d.DialContext(ctx, network, r.server)
+			},
+		}
+	})
+
+	return r.resolver
+}
+
+// LookupHost returns the addresses of host. IP addresses are returned
+// as they are, without a lookup
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return []string{host}, nil
+	}
+
+	return r.lookup(ctx, "host:"+host, func(ctx context.Context) ([]string, error) {
+		return lookupHost(ctx, r.netResolver(), host)
+	})
+}
+
+// LookupAddr returns the names addr resolves back to, without the
+// trailing dot
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return r.lookup(ctx, "addr:"+addr, func(ctx context.Context) ([]string, error) {
+		names, err := lookupAddr(ctx, r.netResolver(), addr)
+		for i := range names {
+			names[i] = strings.TrimSuffix(names[i], ".")
+		}
+		return names, err
+	})
+}
+
+// lookup answers from the cache or runs fn with the timeout of the
+// Resolver. Names that don't exist are cached too, but other errors are
+// not, so a name that timed out is tried again the next time
+func (r *Resolver) lookup(ctx context.Context, key string,
+	fn func(context.Context) ([]string, error)) ([]string, error) {
+
+	r.mu.Lock()
+	c, ok := r.cache[key]
+	r.mu.Unlock()
+
+	now := time.Now()
+	if ok && (r.ttl == 0 || now.Before(c.expires)) {
+		return c.names, c.err
+	}
+
+	timeout := r.timeout
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	names, err := fn(lookupCtx)
+
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return names, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[string]cachedLookup{}
+	}
+	r.cache[key] = cachedLookup{names: names, err: err, expires: now.Add(r.ttl)}
+
+	return names, err
+}
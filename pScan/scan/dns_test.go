@@ -0,0 +1,267 @@
+package scan_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"pScan/scan"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DNS record types answered by dnsServer
+const (
+	typeA   = 1
+	typePTR = 12
+)
+
+// dnsServer is a DNS server answering A and PTR queries from its
+// records, keyed by name with the trailing dot. It counts the queries
+// it gets for each name
+type dnsServer struct {
+	addr    string
+	records map[string][]string
+
+	mu      sync.Mutex
+	queries map[string]int
+}
+
+func startDNS(t *testing.T, records map[string][]string) *dnsServer {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &dnsServer{
+		addr:    conn.LocalAddr().String(),
+		records: records,
+		queries: map[string]int{},
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if reply := s.answer(buf[:n]); reply != nil {
+				conn.WriteTo(reply, from)
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *dnsServer) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[name]
+}
+
+// answer builds the reply to a query with a single question
+func (s *dnsServer) answer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// the question name is a list of labels ending with an empty one
+	labels := []string{}
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		l := int(query[i])
+		if i+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[i+1:i+1+l]))
+		i += 1 + l
+	}
+	if i+5 > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[i+1:])
+	question := query[12 : i+5]
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+
+	s.mu.Lock()
+	s.queries[name]++
+	s.mu.Unlock()
+
+	records, ok := s.records[name]
+
+	reply := make([]byte, 12, 512)
+	copy(reply, query[:2])
+	binary.BigEndian.PutUint16(reply[2:], 0x8180)
+	if !ok {
+		// NXDOMAIN
+		reply[3] |= 3
+	}
+	binary.BigEndian.PutUint16(reply[4:], 1)
+	reply = append(reply, question...)
+
+	answers := 0
+	for _, r := range records {
+		var data []byte
+		switch ip := net.ParseIP(r); {
+		case qtype == typeA && ip != nil && ip.To4() != nil:
+			data = ip.To4()
+		case qtype == typePTR && ip == nil:
+			for _, l := range strings.Split(strings.TrimSuffix(r, "."), ".") {
+				data = append(data, byte(len(l)))
+				data = append(data, l...)
+			}
+			data = append(data, 0)
+		default:
+			continue
+		}
+
+		// the name points back to the question
+		reply = append(reply, 0xc0, 12)
+		reply = binary.BigEndian.AppendUint16(reply, qtype)
+		reply = binary.BigEndian.AppendUint16(reply, 1)
+		reply = binary.BigEndian.AppendUint32(reply, 60)
+		reply = binary.BigEndian.AppendUint16(reply, uint16(len(data)))
+		reply = append(reply, data...)
+		answers++
+	}
+	binary.BigEndian.PutUint16(reply[6:], uint16(answers))
+
+	return reply
+}
+
+func TestNewResolver(t *testing.T) {
+	testCases := []struct {
+		name   string
+		server string
+		expErr error
+	}{
+		{"System", "", nil},
+		{"IPv4", "127.0.0.1", nil},
+		{"IPv4Port", "127.0.0.1:5353", nil},
+		{"IPv6", "::1", nil},
+		{"IPv6Port", "[::1]:5353", nil},
+		{"Name", "dns.example.com", scan.ErrInvalidDNSServer},
+		{"NamePort", "dns.example.com:53", scan.ErrInvalidDNSServer},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := scan.NewResolver(tc.server, 0, 0)
+			if !errors.Is(err, tc.expErr) {
+				t.Errorf("Expected error %v, got %v instead\n", tc.expErr, err)
+			}
+		})
+	}
+}
+
+func TestRunDNS(t *testing.T) {
+	dns := startDNS(t, map[string][]string{
+		"web1.test.":              {"127.0.0.1"},
+		"2.0.0.127.in-addr.arpa.": {"gw.test."},
+	})
+
+	r, err := scan.NewResolver(dns.addr, time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	for _, h := range []string{"web1.test", "127.0.0.2", "missing.test"} {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := scan.Options{
+		Workers:    3,
+		Timeout:    200 * time.Millisecond,
+		Family:     scan.IPv4,
+		Resolver:   r,
+		ReverseDNS: true,
+	}
+
+	expected := []scan.Results{
+		{Host: "web1.test", Address: "127.0.0.1", Addresses: []string{"127.0.0.1"}},
+		{Host: "127.0.0.2", Address: "127.0.0.2", Addresses: []string{"127.0.0.2"},
+			Names: []string{"gw.test"}},
+		{Host: "missing.test", NotFound: true},
+	}
+
+	// the second scan is answered from the cache, names not found
+	// included
+	for i := 0; i < 2; i++ {
+		res := scan.Run(hl, nil, opts)
+		for j := range res {
+			res[j].PortStates = nil
+		}
+
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf("Scan %d: expected %+v, got %+v instead\n", i+1, expected, res)
+		}
+
+		for _, name := range []string{"web1.test.", "missing.test.", "2.0.0.127.in-addr.arpa."} {
+			if n := dns.count(name); n == 0 || n > 2 {
+				t.Errorf("Scan %d: expected %s queried once for each record type, got %d queries instead\n",
+					i+1, name, n)
+			}
+		}
+	}
+}
+
+func TestResolverCacheTTL(t *testing.T) {
+	dns := startDNS(t, map[string][]string{"web1.test.": {"127.0.0.1"}})
+
+	r, err := scan.NewResolver(dns.addr, time.Second, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := r.LookupHost(ctx, "web1.test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached := dns.count("web1.test.")
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := r.LookupHost(ctx, "web1.test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := dns.count("web1.test."); n <= cached {
+		t.Errorf("Expected a new query once the cache expired, got %d queries, %d before\n",
+			n, cached)
+	}
+}
+
+func TestResolverTimeout(t *testing.T) {
+	// a server that never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	r, err := scan.NewResolver(conn.LocalAddr().String(), 100*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = r.LookupHost(context.Background(), "web1.test")
+	if err == nil {
+		t.Fatal("Expected lookup to fail")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected lookup to give up after 100ms, took %s\n", d)
+	}
+}
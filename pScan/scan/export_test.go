@@ -0,0 +1,108 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+)
+
+// SetDialLatency delays every connection made by the scanner by d. It
+// returns a function that restores the default dialer
+func SetDialLatency(d time.Duration) func() {
+	dial := dialTimeout
+	dialTimeout = func(ctx context.Context, network, address string,
+		timeout time.Duration) (net.Conn, error) {
+
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return dial(ctx, network, address, timeout)
+	}
+
+	return func() {
+		dialTimeout = dial
+	}
+}
+
+// DialState exposes the classification of TCP connect errors
+func DialState(err error) string {
+	return dialState(err).String()
+}
+
+// SetProbePort makes the scanner send the named probe to port. It
+// returns a function that restores the default probes
+func SetProbePort(port int, probe string) func() {
+	old, ok := probePorts[port]
+	probePorts[port] = probe
+
+	return func() {
+		if ok {
+			probePorts[port] = old
+			return
+		}
+		delete(probePorts, port)
+	}
+}
+
+// DisablePing stops Discover from sending ICMP echo requests. It returns
+// a function that enables them again
+func DisablePing() func() {
+	pingHost = func(context.Context, string, time.Duration) bool {
+		return false
+	}
+
+	return func() {
+		pingHost = ping
+	}
+}
+
+// Ping exposes the ICMP echo probe
+var Ping = ping
+
+// EchoRequest and Checksum expose the ICMP message encoding
+var (
+	EchoRequest = echoRequest
+	Checksum    = checksum
+)
+
+// Blackhole makes connections to host time out, as if a firewall
+// dropped them. It returns a function that restores the dialer
+func Blackhole(host string) func() {
+	dial := dialTimeout
+	dialTimeout = func(ctx context.Context, network, address string,
+		timeout time.Duration) (net.Conn, error) {
+
+		if h, _, _ := net.SplitHostPort(address); h != host {
+			return dial(ctx, network, address, timeout)
+		}
+
+		select {
+		case <-time.After(timeout):
+		case <-ctx.Done():
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: os.ErrDeadlineExceeded}
+	}
+
+	return func() {
+		dialTimeout = dial
+	}
+}
+
+// SetLookup makes the scanner resolve the hosts in addrs to the given
+// addresses. It returns a function that restores the default resolver
+func SetLookup(addrs map[string][]string) func() {
+	lookup := lookupHost
+	lookupHost = func(ctx context.Context, r *net.Resolver, host string) ([]string, error) {
+		if a, ok := addrs[host]; ok {
+			return a, nil
+		}
+		return lookup(ctx, r, host)
+	}
+
+	return func() {
+		lookupHost = lookup
+	}
+}
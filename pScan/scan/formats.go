@@ -0,0 +1,309 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidFormat = errors.New("Invalid hosts format")
+
+// Formats accepted by Import and Export
+const (
+	FormatNmap  = "nmap"
+	FormatCSV   = "csv"
+	FormatHosts = "hosts"
+	FormatPlain = "plain"
+	FormatYAML  = "yaml"
+	FormatJSON  = "json"
+)
+
+// Skipped is an entry Import didn't add to the list, and why
+type Skipped struct {
+	Entry  string
+	Reason string
+}
+
+// importEntry is a host read from an imported file
+type importEntry struct {
+	host string
+	info HostInfo
+}
+
+// DetectFormat guesses the import format of a file from its name and
+// the start of its content. Files that aren't XML or CSV are read as
+// /etc/hosts files
+func DetectFormat(fileName string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xml":
+		return FormatNmap
+	case ".csv":
+		return FormatCSV
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return FormatNmap
+	}
+
+	return FormatHosts
+}
+
+// Import adds the hosts read from r in the given format to the list,
+// along with the metadata the format carries. Entries that are invalid,
+// already in the list or don't describe a host to scan are skipped and
+// returned with the reason
+func (hl *HostsList) Import(r io.Reader, format string) ([]string, []Skipped, error) {
+	var (
+		entries []importEntry
+		skipped []Skipped
+		err     error
+	)
+
+	switch format {
+	case FormatNmap:
+		entries, skipped, err = readNmap(r)
+	case FormatCSV:
+		entries, skipped, err = readCSV(r)
+	case FormatHosts:
+		entries, skipped, err = readEtcHosts(r)
+	default:
+		return nil, nil, fmt.Errorf("%w: %q, use one of nmap, csv or hosts",
+			ErrInvalidFormat, format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added := []string{}
+	for _, e := range entries {
+		if err := hl.AddWithInfo(e.host, e.info); err != nil {
+			reason := err.Error()
+			if errors.Is(err, ErrExists) {
+				reason = "already in the list"
+			}
+			skipped = append(skipped, Skipped{Entry: e.host, Reason: reason})
+			continue
+		}
+		added = append(added, e.host)
+	}
+
+	return added, skipped, nil
+}
+
+// nmapRun is the part of the nmap XML output read by Import
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+	} `xml:"host"`
+}
+
+// readNmap reads the hosts of an nmap XML report (nmap -oX). Hosts are
+// added by address, with their names as notes. Hosts nmap found down
+// are skipped
+func readNmap(r io.Reader) ([]importEntry, []Skipped, error) {
+	run := nmapRun{}
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, nil, fmt.Errorf("%w: nmap XML: %s", ErrInvalidFormat, err)
+	}
+
+	entries := []importEntry{}
+	skipped := []Skipped{}
+
+	for _, h := range run.Hosts {
+		addr := ""
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				addr = a.Addr
+				break
+			}
+		}
+
+		names := []string{}
+		for _, n := range h.Hostnames {
+			names = append(names, n.Name)
+		}
+
+		switch {
+		case addr == "":
+			skipped = append(skipped, Skipped{Entry: strings.Join(names, " "),
+				Reason: "no IP address"})
+		case h.Status.State != "" && h.Status.State != "up":
+			skipped = append(skipped, Skipped{Entry: addr, Reason: "host " + h.Status.State})
+		default:
+			entries = append(entries, importEntry{host: addr,
+				info: HostInfo{Notes: strings.Join(names, " ")}})
+		}
+	}
+
+	return entries, skipped, nil
+}
+
+// csvColumns are the columns of CSV hosts files
+var csvColumns = []string{"host", "group", "tags", "ports", "notes"}
+
+// readCSV reads hosts from a CSV file. A header row naming the columns
+// in csvColumns lets them come in any order, and other columns are
+// ignored. Without one, the first column holds the hosts. Tags and
+// ports are separated by spaces or semicolons
+func readCSV(r io.Reader) ([]importEntry, []Skipped, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: CSV: %s", ErrInvalidFormat, err)
+	}
+
+	cols := map[string]int{"host": 0}
+	if len(rows) > 0 && isCSVHeader(rows[0]) {
+		cols = map[string]int{}
+		for i, name := range rows[0] {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		rows = rows[1:]
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	list := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.FieldsFunc(s, func(r rune) bool {
+			return r == ';' || r == ' '
+		})
+	}
+
+	entries := []importEntry{}
+	skipped := []Skipped{}
+
+	for _, row := range rows {
+		host := field(row, "host")
+		if host == "" {
+			skipped = append(skipped, Skipped{Entry: strings.Join(row, ","),
+				Reason: "no host"})
+			continue
+		}
+
+		entries = append(entries, importEntry{host: host, info: HostInfo{
+			Group: field(row, "group"),
+			Tags:  list(field(row, "tags")),
+			Ports: list(field(row, "ports")),
+			Notes: field(row, "notes"),
+		}})
+	}
+
+	return entries, skipped, nil
+}
+
+// isCSVHeader reports whether row names the columns, which it must do
+// for the host column at least
+func isCSVHeader(row []string) bool {
+	for _, name := range row {
+		if strings.EqualFold(strings.TrimSpace(name), "host") {
+			return true
+		}
+	}
+	return false
+}
+
+// readEtcHosts reads an /etc/hosts style file, an address followed by
+// its names on every line. Hosts are added by address, with their names
+// as notes. Loopback and multicast addresses are skipped
+func readEtcHosts(r io.Reader) ([]importEntry, []Skipped, error) {
+	entries := []importEntry{}
+	skipped := []Skipped{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+
+		addr, err := netip.ParseAddr(fields[0])
+		switch {
+		case err != nil:
+			skipped = append(skipped, Skipped{Entry: fields[0], Reason: "not an IP address"})
+		case addr.IsLoopback():
+			skipped = append(skipped, Skipped{Entry: fields[0], Reason: "loopback address"})
+		case addr.IsMulticast():
+			skipped = append(skipped, Skipped{Entry: fields[0], Reason: "multicast address"})
+		default:
+			entries = append(entries, importEntry{host: fields[0],
+				info: HostInfo{Notes: strings.Join(fields[1:], " ")}})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return entries, skipped, nil
+}
+
+// Export writes the list to w in the given format: csv, plain, which
+// nmap reads with -iL, yaml or json
+func (hl *HostsList) Export(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvColumns)
+		for _, h := range hl.Hosts {
+			info := hl.Info[h]
+			cw.Write([]string{h, info.Group, strings.Join(info.Tags, ";"),
+				strings.Join(info.Ports, ";"), info.Notes})
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatPlain:
+		for _, h := range hl.Hosts {
+			if _, err := fmt.Fprintln(w, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		if _, err := io.WriteString(w, "# pScan hosts\n"); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(hl.entries()); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hl.entries())
+	}
+
+	return fmt.Errorf("%w: %q, use one of csv, plain, yaml or json",
+		ErrInvalidFormat, format)
+}
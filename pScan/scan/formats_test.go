@@ -0,0 +1,228 @@
+package scan_test
+
+import (
+	"bytes"
+	"errors"
+	"pScan/scan"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nmapReport = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -oX - 10.0.0.0/29">
+  <host>
+    <status state="up" reason="syn-ack"/>
+    <address addr="10.0.0.1" addrtype="ipv4"/>
+    <address addr="00:11:22:33:44:55" addrtype="mac"/>
+    <hostnames>
+      <hostname name="gw.example.com" type="PTR"/>
+    </hostnames>
+  </host>
+  <host>
+    <status state="down" reason="no-response"/>
+    <address addr="10.0.0.2" addrtype="ipv4"/>
+  </host>
+  <host>
+    <status state="up" reason="echo-reply"/>
+    <address addr="2001:db8::3" addrtype="ipv6"/>
+  </host>
+  <host>
+    <status state="up" reason="echo-reply"/>
+    <address addr="host1" addrtype="ipv4"/>
+  </host>
+</nmaprun>
+`
+
+func TestImport(t *testing.T) {
+	testCases := []struct {
+		name          string
+		format        string
+		content       string
+		expectAdded   []string
+		expectInfo    map[string]scan.HostInfo
+		expectSkipped []scan.Skipped
+	}{
+		{
+			name:        "Nmap",
+			format:      scan.FormatNmap,
+			content:     nmapReport,
+			expectAdded: []string{"10.0.0.1", "2001:db8::3"},
+			expectInfo: map[string]scan.HostInfo{
+				"10.0.0.1": {Notes: "gw.example.com"},
+			},
+			expectSkipped: []scan.Skipped{
+				{Entry: "10.0.0.2", Reason: "host down"},
+				{Entry: "host1", Reason: "already in the list"},
+			},
+		},
+		{
+			name:   "CSVHeader",
+			format: scan.FormatCSV,
+			content: "notes,host,tags,ports,group,owner\n" +
+				"Public site,web1,prod;eu,80;443,web,ops\n" +
+				",10.0.0.0/30,,,,\n" +
+				"no host here,,,,,\n" +
+				",host_2,,,,\n",
+			expectAdded: []string{"web1", "10.0.0.0/30"},
+			expectInfo: map[string]scan.HostInfo{
+				"web1": {Group: "web", Tags: []string{"prod", "eu"},
+					Ports: []string{"80", "443"}, Notes: "Public site"},
+			},
+			expectSkipped: []scan.Skipped{
+				{Entry: "no host here,,,,,", Reason: "no host"},
+				{Entry: "host_2", Reason: `Invalid host: "host_2" is not a host name, address, CIDR block or range`},
+			},
+		},
+		{
+			name:          "CSVNoHeader",
+			format:        scan.FormatCSV,
+			content:       "web1,ignored\n# a comment\nweb2\nweb1\n",
+			expectAdded:   []string{"web1", "web2"},
+			expectSkipped: []scan.Skipped{{Entry: "web1", Reason: "already in the list"}},
+		},
+		{
+			name:   "EtcHosts",
+			format: scan.FormatHosts,
+			content: "127.0.0.1\tlocalhost\n::1 ip6-localhost ip6-loopback\n" +
+				"ff02::1 ip6-allnodes\n\n# servers\n10.0.0.5  db1 db1.example.com\n" +
+				"10.0.0.6 app1 # comment\nbogus line\n",
+			expectAdded: []string{"10.0.0.5", "10.0.0.6"},
+			expectInfo: map[string]scan.HostInfo{
+				"10.0.0.5": {Notes: "db1 db1.example.com"},
+				"10.0.0.6": {Notes: "app1"},
+			},
+			expectSkipped: []scan.Skipped{
+				{Entry: "127.0.0.1", Reason: "loopback address"},
+				{Entry: "::1", Reason: "loopback address"},
+				{Entry: "ff02::1", Reason: "multicast address"},
+				{Entry: "bogus", Reason: "not an IP address"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := &scan.HostsList{}
+			if err := hl.Add("host1"); err != nil {
+				t.Fatal(err)
+			}
+
+			added, skipped, err := hl.Import(strings.NewReader(tc.content), tc.format)
+			if err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if !reflect.DeepEqual(added, tc.expectAdded) {
+				t.Errorf("Expected added %q, got %q instead\n", tc.expectAdded, added)
+			}
+
+			if len(skipped) != len(tc.expectSkipped) ||
+				(len(skipped) > 0 && !reflect.DeepEqual(skipped, tc.expectSkipped)) {
+				t.Errorf("Expected skipped %q, got %q instead\n", tc.expectSkipped, skipped)
+			}
+
+			if len(hl.Hosts) != len(tc.expectAdded)+1 {
+				t.Errorf("Expected %d hosts in the list, got %q instead\n",
+					len(tc.expectAdded)+1, hl.Hosts)
+			}
+
+			for h, info := range tc.expectInfo {
+				if !reflect.DeepEqual(hl.Info[h], info) {
+					t.Errorf("Expected info %+v for %s, got %+v instead\n", info, h, hl.Info[h])
+				}
+			}
+		})
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{"UnknownFormat", "xls", "host1\n"},
+		{"BrokenXML", scan.FormatNmap, "<nmaprun><host>"},
+		{"BrokenCSV", scan.FormatCSV, "host,\"notes\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := &scan.HostsList{}
+			_, _, err := hl.Import(strings.NewReader(tc.content), tc.format)
+			if !errors.Is(err, scan.ErrInvalidFormat) {
+				t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidFormat, err)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		file    string
+		content string
+		expect  string
+	}{
+		{"scan.xml", "", scan.FormatNmap},
+		{"hosts.CSV", "", scan.FormatCSV},
+		{"report", "\n  <?xml version=\"1.0\"?>", scan.FormatNmap},
+		{"/etc/hosts", "127.0.0.1 localhost\n", scan.FormatHosts},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			if f := scan.DetectFormat(tc.file, []byte(tc.content)); f != tc.expect {
+				t.Errorf("Expected format %q, got %q instead\n", tc.expect, f)
+			}
+		})
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	hl := &scan.HostsList{}
+	hl.AddWithInfo("web1", scan.HostInfo{Group: "web", Tags: []string{"prod", "eu"},
+		Ports: []string{"80", "443"}, Notes: "Public, site"})
+	hl.Add("10.0.0.0/30")
+
+	testCases := []struct {
+		format string
+		expect string
+	}{
+		{scan.FormatCSV, "host,group,tags,ports,notes\n" +
+			"web1,web,prod;eu,80;443,\"Public, site\"\n10.0.0.0/30,,,,\n"},
+		{scan.FormatPlain, "web1\n10.0.0.0/30\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := hl.Export(&out, tc.format); err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if out.String() != tc.expect {
+				t.Errorf("Expected %q, got %q instead\n", tc.expect, out.String())
+			}
+		})
+	}
+
+	// CSV keeps everything the list knows about the hosts
+	var out bytes.Buffer
+	if err := hl.Export(&out, scan.FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	hl2 := &scan.HostsList{}
+	if _, _, err := hl2.Import(&out, scan.FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(hl.Hosts, hl2.Hosts) || !reflect.DeepEqual(hl.Info, hl2.Info) {
+		t.Errorf("Expected list %+v, got %+v instead\n", hl, hl2)
+	}
+
+	if err := hl.Export(&out, "xml"); !errors.Is(err, scan.ErrInvalidFormat) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidFormat, err)
+	}
+}
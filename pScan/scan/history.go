@@ -0,0 +1,266 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrScanNotFound = errors.New("Scan not in the history")
+
+// Record is a scan saved in the history
+type Record struct {
+	ID         int       `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Results    []Results `json:"results"`
+}
+
+// OpenPorts counts the open ports found by the scan
+func (r Record) OpenPorts() int {
+	n := 0
+	for _, res := range r.Results {
+		for _, p := range res.PortStates {
+			if p.State == StateOpen {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// History represents the scans saved in a history file. The file holds
+// one JSON record per line, so saving a scan only appends to it
+type History struct {
+	Records []Record
+}
+
+// Load obtains the scans from a history file
+func (h *History) Load(historyFile string) error {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// a record holds the whole scan, which is larger than the default
+	// line limit
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		r := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("%s:%d: %w", historyFile, line, err)
+		}
+		h.Records = append(h.Records, r)
+	}
+
+	return scanner.Err()
+}
+
+// Append saves r at the end of the history file, numbering it after the
+// last scan in the history. It returns the record as saved
+func (h *History) Append(historyFile string, r Record) (Record, error) {
+	r.ID = 1
+	if n := len(h.Records); n > 0 {
+		r.ID = h.Records[n-1].ID + 1
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return r, err
+	}
+
+	f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return r, err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return r, err
+	}
+
+	if err := f.Close(); err != nil {
+		return r, err
+	}
+
+	h.Records = append(h.Records, r)
+	return r, nil
+}
+
+// Get returns the scan with the given ID
+func (h *History) Get(id int) (Record, error) {
+	for _, r := range h.Records {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Record{}, fmt.Errorf("%w: %d", ErrScanNotFound, id)
+}
+
+// PortChange is a port that changed state between two scans
+type PortChange struct {
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+	Proto string `json:"proto"`
+}
+
+// Diff lists the changes between two scans
+type Diff struct {
+	// HostsAppeared are found in the newer scan only
+	HostsAppeared []string
+	// HostsDisappeared are found in the older scan only
+	HostsDisappeared []string
+	// Opened are ports open in the newer scan that weren't in the older
+	Opened []PortChange
+	// Closed are ports open in the older scan that aren't anymore
+	Closed []PortChange
+}
+
+// Empty reports whether the scans had the same results
+func (d Diff) Empty() bool {
+	return len(d.HostsAppeared) == 0 && len(d.HostsDisappeared) == 0 &&
+		len(d.Opened) == 0 && len(d.Closed) == 0
+}
+
+// String lists the changes one per line, prefixed with + for hosts and
+// ports that appeared and - for the ones that went away
+func (d Diff) String() string {
+	s := ""
+
+	for _, h := range d.HostsAppeared {
+		s += fmt.Sprintf("+ %s: host appeared\n", h)
+	}
+	for _, h := range d.HostsDisappeared {
+		s += fmt.Sprintf("- %s: host disappeared\n", h)
+	}
+	for _, c := range d.Opened {
+		s += fmt.Sprintf("+ %s: %d/%s opened\n", c.Host, c.Port, c.Proto)
+	}
+	for _, c := range d.Closed {
+		s += fmt.Sprintf("- %s: %d/%s closed\n", c.Host, c.Port, c.Proto)
+	}
+
+	return s
+}
+
+// Compare returns what changed from scan a to scan b. Ports are only
+// compared on hosts found by both scans, and only if both scanned them.
+// Hosts with several addresses are compared address by address
+func Compare(a, b Record) Diff {
+	d := Diff{}
+
+	older := foundHosts(a)
+	newer := foundHosts(b)
+
+	for _, res := range a.Results {
+		if _, ok := older.match(res); ok {
+			if _, ok := newer.match(res); !ok {
+				d.HostsDisappeared = append(d.HostsDisappeared, res.Name())
+			}
+		}
+	}
+
+	for _, res := range b.Results {
+		before, ok := older.match(res)
+		if !ok {
+			if _, found := newer.match(res); found {
+				d.HostsAppeared = append(d.HostsAppeared, res.Name())
+			}
+			continue
+		}
+
+		for _, p := range res.PortStates {
+			prev, scanned := before[portKey{p.Port, p.Proto}]
+			if !scanned {
+				continue
+			}
+
+			c := PortChange{Host: res.Name(), Port: p.Port, Proto: p.Proto}
+			switch {
+			case p.State == StateOpen && prev != StateOpen:
+				d.Opened = append(d.Opened, c)
+			case p.State != StateOpen && prev == StateOpen:
+				d.Closed = append(d.Closed, c)
+			}
+		}
+	}
+
+	return d
+}
+
+type portKey struct {
+	port  int
+	proto string
+}
+
+// found holds the hosts found by a scan and the state of their ports
+type found struct {
+	// names maps Results.Name to the ports of the address
+	names map[string]map[portKey]state
+	// hosts maps every host to the names of its addresses, in order
+	hosts map[string][]string
+	// legacy holds the hosts saved without an address, before scans
+	// were split by address
+	legacy map[string]bool
+}
+
+// foundHosts maps the hosts found by a scan to the state of their ports
+func foundHosts(r Record) found {
+	f := found{
+		names:  map[string]map[portKey]state{},
+		hosts:  map[string][]string{},
+		legacy: map[string]bool{},
+	}
+
+	for _, res := range r.Results {
+		if res.NotFound {
+			continue
+		}
+
+		ports := map[portKey]state{}
+		for _, p := range res.PortStates {
+			ports[portKey{p.Port, p.Proto}] = p.State
+		}
+
+		f.names[res.Name()] = ports
+		f.hosts[res.Host] = append(f.hosts[res.Host], res.Name())
+		if res.Address == "" {
+			f.legacy[res.Host] = true
+		}
+	}
+
+	return f
+}
+
+// match returns the ports of res if the scan found it. Results saved
+// before scans were split by address have no address, so they stand
+// for every address of their host, on either side
+func (f found) match(res Results) (map[portKey]state, bool) {
+	if ports, ok := f.names[res.Name()]; ok {
+		return ports, true
+	}
+
+	if f.legacy[res.Host] {
+		ports, ok := f.names[res.Host]
+		return ports, ok
+	}
+
+	if names := f.hosts[res.Host]; res.Address == "" && len(names) > 0 {
+		return f.names[names[0]], true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,173 @@
+package scan_test
+
+import (
+	"errors"
+	"pScan/scan"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func record(results ...scan.Results) scan.Record {
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	return scan.Record{
+		StartedAt:  start,
+		FinishedAt: start.Add(time.Second),
+		Results:    results,
+	}
+}
+
+func host(name string, open []int, closed []int) scan.Results {
+	r := scan.Results{Host: name}
+	for _, p := range open {
+		r.PortStates = append(r.PortStates,
+			scan.PortState{Port: p, Proto: scan.TCP, State: scan.StateOpen})
+	}
+	for _, p := range closed {
+		r.PortStates = append(r.PortStates,
+			scan.PortState{Port: p, Proto: scan.TCP, State: scan.StateClosed})
+	}
+	return r
+}
+
+// addr returns r as the result for addr, one of the addresses of a host
+func addr(r scan.Results, addr string, addrs ...string) scan.Results {
+	r.Address = addr
+	r.Addresses = addrs
+	return r
+}
+
+func TestHistoryAppendLoad(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "pScan.history")
+
+	first := record(host("host1", []int{22}, []int{80}))
+	first.Results[0].PortStates[0].Fingerprint = &scan.Fingerprint{
+		Service: "ssh",
+		Version: "OpenSSH_8.9p1",
+	}
+	second := record(host("host1", []int{22, 80}, nil),
+		scan.Results{Host: "host2", NotFound: true})
+
+	h1 := &scan.History{}
+	if err := h1.Load(historyFile); err != nil {
+		t.Fatalf("Expected no error loading a missing file, got %q\n", err)
+	}
+
+	for i, r := range []scan.Record{first, second} {
+		saved, err := h1.Append(historyFile, r)
+		if err != nil {
+			t.Fatalf("Expected no error, got %q instead\n", err)
+		}
+		if saved.ID != i+1 {
+			t.Errorf("Expected ID %d, got %d instead\n", i+1, saved.ID)
+		}
+	}
+
+	h2 := &scan.History{}
+	if err := h2.Load(historyFile); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	if !reflect.DeepEqual(h1.Records, h2.Records) {
+		t.Errorf("Expected records %+v, got %+v instead\n", h1.Records, h2.Records)
+	}
+
+	r, err := h2.Get(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+	if r.OpenPorts() != 2 {
+		t.Errorf("Expected 2 open ports, got %d instead\n", r.OpenPorts())
+	}
+
+	if _, err := h2.Get(3); !errors.Is(err, scan.ErrScanNotFound) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrScanNotFound, err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		name   string
+		a, b   scan.Record
+		expect scan.Diff
+	}{
+		{
+			name:   "NoChanges",
+			a:      record(host("host1", []int{22}, []int{80})),
+			b:      record(host("host1", []int{22}, []int{80})),
+			expect: scan.Diff{},
+		},
+		{
+			name: "Ports",
+			a:    record(host("host1", []int{22}, []int{80})),
+			b:    record(host("host1", []int{80}, []int{22})),
+			expect: scan.Diff{
+				Opened: []scan.PortChange{{Host: "host1", Port: 80, Proto: scan.TCP}},
+				Closed: []scan.PortChange{{Host: "host1", Port: 22, Proto: scan.TCP}},
+			},
+		},
+		{
+			name:   "PortNotScannedBefore",
+			a:      record(host("host1", []int{22}, nil)),
+			b:      record(host("host1", []int{22, 443}, nil)),
+			expect: scan.Diff{},
+		},
+		{
+			name: "Hosts",
+			a: record(host("host1", []int{22}, nil),
+				scan.Results{Host: "host2", NotFound: true}),
+			b: record(scan.Results{Host: "host1", NotFound: true},
+				host("host2", []int{22}, nil),
+				host("host3", nil, nil)),
+			expect: scan.Diff{
+				HostsAppeared:    []string{"host2", "host3"},
+				HostsDisappeared: []string{"host1"},
+			},
+		},
+		{
+			name: "Addresses",
+			a: record(addr(host("host1", []int{22}, nil), "10.0.0.1", "10.0.0.1", "::1"),
+				addr(host("host1", []int{22}, nil), "::1", "10.0.0.1", "::1")),
+			b: record(addr(host("host1", []int{22}, nil), "10.0.0.1", "10.0.0.1", "::1"),
+				addr(host("host1", nil, []int{22}), "::1", "10.0.0.1", "::1"),
+				addr(host("host1", nil, nil), "::2", "10.0.0.1", "::1", "::2")),
+			expect: scan.Diff{
+				HostsAppeared: []string{"host1 (::2)"},
+				Closed:        []scan.PortChange{{Host: "host1 (::1)", Port: 22, Proto: scan.TCP}},
+			},
+		},
+		{
+			// records saved before scans were split by address
+			name: "LegacyBefore",
+			a:    record(host("host1", []int{22}, nil)),
+			b: record(addr(host("host1", []int{22, 80}, nil), "10.0.0.1", "10.0.0.1", "::1"),
+				addr(host("host1", nil, []int{22}), "::1", "10.0.0.1", "::1")),
+			expect: scan.Diff{
+				Closed: []scan.PortChange{{Host: "host1 (::1)", Port: 22, Proto: scan.TCP}},
+			},
+		},
+		{
+			name: "LegacyAfter",
+			a: record(addr(host("host1", []int{22}, nil), "10.0.0.1", "10.0.0.1", "::1"),
+				addr(host("host1", []int{22}, nil), "::1", "10.0.0.1", "::1")),
+			b:      record(host("host1", []int{22}, nil)),
+			expect: scan.Diff{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := scan.Compare(tc.a, tc.b)
+
+			if !reflect.DeepEqual(d, tc.expect) {
+				t.Errorf("Expected diff %+v, got %+v instead\n", tc.expect, d)
+			}
+
+			empty := reflect.DeepEqual(tc.expect, scan.Diff{})
+			if d.Empty() != empty {
+				t.Errorf("Expected Empty to be %t\n", empty)
+			}
+		})
+	}
+}
@@ -2,10 +2,15 @@ package scan
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"sort"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -13,70 +18,331 @@ var (
 	ErrNotExists = errors.New("Host not in the list")
 )
 
-// HostsList represents a list of hosts to run port scan
-// Wrap the slice because Hosts does not have any special fields
-// that require that you define a new type.
+// HostsList represents a list of hosts to run port scan. Hosts keeps
+// the order in which hosts were added, and an index of their positions
+// keeps lookups fast on large lists
 type HostsList struct {
 	Hosts []string
+	// Info holds the metadata of the hosts that have any, by host
+	Info map[string]HostInfo
+
+	index map[string]int
+}
+
+// HostInfo is the metadata kept for a host in a structured hosts file
+type HostInfo struct {
+	Group string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Tags  []string `json:"tags,omitempty" yaml:"tags,omitempty,flow"`
+	// Ports replaces the ports given to Run for this host. Entries use
+	// the syntax accepted by ParsePorts
+	Ports []string `json:"ports,omitempty" yaml:"ports,omitempty,flow"`
+	Notes string   `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// empty reports whether i holds no metadata
+func (i HostInfo) empty() bool {
+	return i.Group == "" && len(i.Tags) == 0 && len(i.Ports) == 0 && i.Notes == ""
+}
+
+// HasTag reports whether the host is tagged with tag
+func (i HostInfo) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// hostEntry is a host as written in a structured hosts file
+type hostEntry struct {
+	Name     string `json:"name" yaml:"name"`
+	HostInfo `yaml:",inline"`
+}
+
+// hostsDoc is the layout of a structured hosts file
+type hostsDoc struct {
+	Hosts []hostEntry `json:"hosts" yaml:"hosts"`
+}
+
+// Normalize returns the form hosts are kept in the list: without
+// surrounding spaces or a trailing dot, and in lower case since host
+// names are case insensitive
+func Normalize(host string) string {
+	host = strings.TrimSpace(host)
+	if !strings.Contains(host, "/") {
+		host = strings.TrimSuffix(host, ".")
+	}
+	return strings.ToLower(host)
 }
 
 // search searches for hosts in the list
 func (hl *HostsList) search(host string) (bool, int) {
-	sort.Strings(hl.Hosts)
+	// Hosts is exported, so it may have changed behind the index
+	if hl.index == nil || len(hl.index) != len(hl.Hosts) {
+		hl.reindex()
+	}
+
+	i, ok := hl.index[host]
+	if !ok || hl.Hosts[i] != host {
+		return false, -1
+	}
+	return true, i
+}
 
-	i := sort.SearchStrings(hl.Hosts, host)
-	if i < len(hl.Hosts) && hl.Hosts[i] == host {
-		return true, i
+func (hl *HostsList) reindex() {
+	hl.index = make(map[string]int, len(hl.Hosts))
+	for i, h := range hl.Hosts {
+		hl.index[h] = i
 	}
-	return false, -1
 }
 
-// Add adds a host to the list
+// Add adds a host to the list. Entries may also be CIDR blocks or
+// address ranges, which are expanded when the list is scanned
 func (hl *HostsList) Add(host string) error {
+	return hl.AddWithInfo(host, HostInfo{})
+}
+
+// AddWithInfo adds a host to the list along with its metadata. The
+// host is normalized first, so names differing only in case or a
+// trailing dot are the same host
+func (hl *HostsList) AddWithInfo(host string, info HostInfo) error {
+	host = Normalize(host)
+
+	if err := ValidateHost(host); err != nil {
+		return err
+	}
+
+	if _, err := ParsePorts(info.Ports); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+
 	if found, _ := hl.search(host); found {
 		return fmt.Errorf("%w: %s", ErrExists, host)
 	}
 
 	hl.Hosts = append(hl.Hosts, host)
+	hl.index[host] = len(hl.Hosts) - 1
+	hl.setInfo(host, info)
 	return nil
 }
 
-// Remove deletes a host from the list
+func (hl *HostsList) setInfo(host string, info HostInfo) {
+	if info.empty() {
+		return
+	}
+	if hl.Info == nil {
+		hl.Info = map[string]HostInfo{}
+	}
+	hl.Info[host] = info
+}
+
+// Remove deletes a host from the list, keeping the order of the rest
 func (hl *HostsList) Remove(host string) error {
-	if found, i := hl.search(host); found {
-		hl.Hosts = append(hl.Hosts[:i], hl.Hosts[i+1:]...)
-		return nil
+	host = Normalize(host)
+
+	found, i := hl.search(host)
+	if !found {
+		return fmt.Errorf("%w: %s", ErrNotExists, host)
+	}
+
+	hl.Hosts = append(hl.Hosts[:i], hl.Hosts[i+1:]...)
+	delete(hl.index, host)
+	for j := i; j < len(hl.Hosts); j++ {
+		hl.index[hl.Hosts[j]] = j
+	}
+	delete(hl.Info, host)
+	return nil
+}
+
+// Match reports whether host belongs to group and has all of tags. An
+// empty group matches every host
+func (hl *HostsList) Match(host, group string, tags []string) bool {
+	info := hl.Info[host]
+
+	if group != "" && info.Group != group {
+		return false
 	}
-	return fmt.Errorf("%w: %s", ErrNotExists, host)
+
+	for _, t := range tags {
+		if !info.HasTag(t) {
+			return false
+		}
+	}
+
+	return true
 }
 
-// Load obtains hosts from a hosts file
+// Load obtains hosts from a hosts file. Files ending in .yaml, .yml or
+// .json, or starting with a hosts key, are read as structured files.
+// Anything else is read in the plain format, one host per line, where
+// blank lines and text after a # are ignored. Hosts are normalized as
+// Add does, and hosts listed twice are kept once, with the metadata of
+// their first entry
 func (hl *HostsList) Load(hostsFile string) error {
-	f, err := os.Open(hostsFile)
+	data, err := os.ReadFile(hostsFile)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return err
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	if structured(hostsFile, data) {
+		return hl.loadStructured(hostsFile, data)
+	}
 
-	for scanner.Scan() {
-		hl.Hosts = append(hl.Hosts, scanner.Text())
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for line := 1; scanner.Scan(); line++ {
+		h := stripComment(scanner.Text())
+		if h == "" {
+			continue
+		}
+
+		if err := hl.load(h, HostInfo{}); err != nil {
+			return fmt.Errorf("%s:%d: %w", hostsFile, line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// loadStructured reads a YAML hosts file. JSON files are read the same
+// way since JSON is valid YAML
+func (hl *HostsList) loadStructured(hostsFile string, data []byte) error {
+	f := hostsDoc{}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %w", hostsFile, err)
 	}
 
+	for _, e := range f.Hosts {
+		if err := hl.load(e.Name, e.HostInfo); err != nil {
+			return fmt.Errorf("%s: %w", hostsFile, err)
+		}
+	}
+
+	return nil
+}
+
+// load adds a host read from a hosts file, ignoring duplicates. Unlike
+// AddWithInfo it keeps names that don't validate, such as my_db, which
+// older versions accepted. They can still be listed and removed, and
+// scans look them up like any other name
+func (hl *HostsList) load(host string, info HostInfo) error {
+	host = Normalize(host)
+	if host == "" {
+		return nil
+	}
+
+	if _, err := ParsePorts(info.Ports); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+
+	if found, _ := hl.search(host); found {
+		return nil
+	}
+
+	hl.Hosts = append(hl.Hosts, host)
+	hl.index[host] = len(hl.Hosts) - 1
+	hl.setInfo(host, info)
 	return nil
 }
 
-// Save saves hosts to a hosts file
+// structured reports whether a hosts file uses the structured format
+func structured(hostsFile string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(hostsFile)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := stripComment(scanner.Text())
+		if line == "" {
+			continue
+		}
+		return strings.HasPrefix(line, "hosts:") || strings.HasPrefix(line, "{")
+	}
+
+	return false
+}
+
+func stripComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// Save saves hosts to a hosts file. Lists without metadata are saved
+// in the plain format unless the file name asks for YAML or JSON, so
+// existing plain files stay plain
 func (hl *HostsList) Save(hostsFile string) error {
-	output := ""
+	format := FormatPlain
+
+	switch ext := strings.ToLower(filepath.Ext(hostsFile)); {
+	case ext == ".json":
+		format = FormatJSON
+	case len(hl.Info) > 0 || ext == ".yaml" || ext == ".yml":
+		format = FormatYAML
+	}
+
+	return writeAtomic(hostsFile, func(w io.Writer) error {
+		return hl.Export(w, format)
+	})
+}
+
+// writeAtomic streams the content written by write to a temporary file
+// next to fileName, and then moves it over fileName. Readers see either
+// the old or the new file, never a partial one
+func writeAtomic(fileName string, write func(w io.Writer) error) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(fileName); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	// nothing is left to remove once the file is renamed
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), fileName)
+}
+
+func (hl *HostsList) entries() hostsDoc {
+	f := hostsDoc{Hosts: []hostEntry{}}
 
 	for _, h := range hl.Hosts {
-		output += fmt.Sprintln(h)
+		f.Hosts = append(f.Hosts, hostEntry{Name: h, HostInfo: hl.Info[h]})
 	}
 
-	return os.WriteFile(hostsFile, []byte(output), 0644)
+	return f
 }
@@ -2,9 +2,13 @@ package scan_test
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"pScan/scan"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -17,6 +21,8 @@ func TestAdd(t *testing.T) {
 	}{
 		{"AddNew", "host2", 2, nil},
 		{"AddExisting", "host1", 1, scan.ErrExists},
+		{"AddCIDR", "10.0.0.0/28", 2, nil},
+		{"AddInvalid", "host_2", 1, scan.ErrInvalidHost},
 	}
 
 	for _, tc := range testCases {
@@ -160,3 +166,381 @@ func TestLoadNoFile(t *testing.T) {
 		t.Errorf("Expected no error, got %q instead\n", err)
 	}
 }
+
+func TestLoadFormats(t *testing.T) {
+	expHosts := []string{"host1", "10.0.0.0/28", "host2"}
+	expInfo := map[string]scan.HostInfo{
+		"host1": {Group: "web", Tags: []string{"prod", "eu"},
+			Ports: []string{"80", "443"}, Notes: "Public site"},
+		"host2": {Tags: []string{"dev"}},
+	}
+
+	testCases := []struct {
+		name       string
+		file       string
+		content    string
+		expectInfo map[string]scan.HostInfo
+	}{
+		{
+			name: "Plain",
+			file: "pScan.hosts",
+			content: "# office network\n  host1  \n\n10.0.0.0/28 # printers\n" +
+				"\thost2\n",
+		},
+		{
+			name: "YAML",
+			file: "pScan.hosts",
+			content: `# pScan hosts
+hosts:
+  - name: host1
+    group: web
+    tags: [prod, eu]
+    ports: [80, 443]
+    notes: Public site
+  # expanded when scanned
+  - name: 10.0.0.0/28
+  - name: host2
+    tags: [dev]
+`,
+			expectInfo: expInfo,
+		},
+		{
+			name: "JSON",
+			file: "hosts.json",
+			content: `{"hosts": [
+  {"name": " host1 ", "group": "web", "tags": ["prod", "eu"],
+   "ports": ["80", "443"], "notes": "Public site"},
+  {"name": "10.0.0.0/28"},
+  {"name": "host2", "tags": ["dev"]}
+]}`,
+			expectInfo: expInfo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hostsFile := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(hostsFile, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			hl := &scan.HostsList{}
+			if err := hl.Load(hostsFile); err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if !reflect.DeepEqual(hl.Hosts, expHosts) {
+				t.Errorf("Expected hosts %q, got %q instead\n", expHosts, hl.Hosts)
+			}
+
+			if !reflect.DeepEqual(hl.Info, tc.expectInfo) {
+				t.Errorf("Expected info %+v, got %+v instead\n", tc.expectInfo, hl.Info)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidPorts(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "hosts.yaml")
+	content := "hosts:\n  - name: host1\n    ports: [http]\n"
+	if err := os.WriteFile(hostsFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); !errors.Is(err, scan.ErrInvalidPorts) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidPorts, err)
+	}
+
+	if err := hl.AddWithInfo("host2", scan.HostInfo{Ports: []string{"0"}}); !errors.Is(err, scan.ErrInvalidPorts) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidPorts, err)
+	}
+}
+
+func TestSaveLoadInfo(t *testing.T) {
+	testCases := []struct {
+		name         string
+		file         string
+		info         scan.HostInfo
+		expectPrefix string
+	}{
+		{"PlainNoInfo", "pScan.hosts", scan.HostInfo{}, "host1\n"},
+		{"YAMLWithInfo", "pScan.hosts", scan.HostInfo{Group: "db"}, "# pScan hosts\nhosts:"},
+		{"YAMLByName", "hosts.yml", scan.HostInfo{}, "# pScan hosts\nhosts:"},
+		{"JSON", "hosts.json", scan.HostInfo{Tags: []string{"prod"}}, "{"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hostsFile := filepath.Join(t.TempDir(), tc.file)
+
+			hl1 := &scan.HostsList{}
+			if err := hl1.AddWithInfo("host1", tc.info); err != nil {
+				t.Fatal(err)
+			}
+			if err := hl1.Add("host2"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := hl1.Save(hostsFile); err != nil {
+				t.Fatalf("Error saving list to file: %s", err)
+			}
+
+			data, err := os.ReadFile(hostsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), tc.expectPrefix) {
+				t.Errorf("Expected file to start with %q, got %q instead\n",
+					tc.expectPrefix, data)
+			}
+
+			hl2 := &scan.HostsList{}
+			if err := hl2.Load(hostsFile); err != nil {
+				t.Fatalf("Error getting list from file: %s", err)
+			}
+
+			if !reflect.DeepEqual(hl1.Hosts, hl2.Hosts) ||
+				!reflect.DeepEqual(hl1.Info, hl2.Info) {
+				t.Errorf("Expected list %+v, got %+v instead\n", hl1, hl2)
+			}
+		})
+	}
+}
+
+func TestLoadNormalize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		file       string
+		content    string
+		expectInfo map[string]scan.HostInfo
+	}{
+		{
+			name:    "Plain",
+			file:    "pScan.hosts",
+			content: "Host1.Example.com.\nhost2\nhost1.example.com\n10.0.0.0/28\nHOST2 # again\n",
+		},
+		{
+			name: "YAML",
+			file: "hosts.yaml",
+			content: "hosts:\n  - name: Host1.Example.COM\n    group: web\n  - name: host2\n" +
+				"  - name: host1.example.com.\n    group: db\n  - name: 10.0.0.0/28\n",
+			expectInfo: map[string]scan.HostInfo{"host1.example.com": {Group: "web"}},
+		},
+	}
+
+	expHosts := []string{"host1.example.com", "host2", "10.0.0.0/28"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hostsFile := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(hostsFile, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			hl := &scan.HostsList{}
+			if err := hl.Load(hostsFile); err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if !reflect.DeepEqual(hl.Hosts, expHosts) {
+				t.Errorf("Expected hosts %q, got %q instead\n", expHosts, hl.Hosts)
+			}
+
+			if !reflect.DeepEqual(hl.Info, tc.expectInfo) {
+				t.Errorf("Expected info %+v, got %+v instead\n", tc.expectInfo, hl.Info)
+			}
+
+			if err := hl.Add("HOST2."); !errors.Is(err, scan.ErrExists) {
+				t.Errorf("Expected error %q, got %q instead\n", scan.ErrExists, err)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidHost(t *testing.T) {
+	// older versions added names such as my_db without validating them
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+	if err := os.WriteFile(hostsFile, []byte("host1\n\nMy_DB\nmy_db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	if exp := []string{"host1", "my_db"}; !reflect.DeepEqual(hl.Hosts, exp) {
+		t.Errorf("Expected hosts %q, got %q instead\n", exp, hl.Hosts)
+	}
+
+	// new entries are still validated
+	if err := hl.Add("host_2"); !errors.Is(err, scan.ErrInvalidHost) {
+		t.Errorf("Expected error %q, got %q instead\n", scan.ErrInvalidHost, err)
+	}
+
+	if err := hl.Remove("my_db"); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+	if err := hl.Save(hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	hl2 := &scan.HostsList{}
+	if err := hl2.Load(hostsFile); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"host1"}; !reflect.DeepEqual(hl2.Hosts, exp) {
+		t.Errorf("Expected hosts %q, got %q instead\n", exp, hl2.Hosts)
+	}
+}
+
+func TestStableOrder(t *testing.T) {
+	hl := &scan.HostsList{}
+	for _, h := range []string{"host3", "host1", "host4", "host2"} {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := hl.Remove("host1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hl.Add("host0"); err != nil {
+		t.Fatal(err)
+	}
+	// hosts set directly are found as well
+	hl.Hosts = append(hl.Hosts, "host5")
+
+	exp := []string{"host3", "host4", "host2", "host0", "host5"}
+	if !reflect.DeepEqual(hl.Hosts, exp) {
+		t.Errorf("Expected hosts %q, got %q instead\n", exp, hl.Hosts)
+	}
+
+	for _, h := range exp {
+		if err := hl.Add(h); !errors.Is(err, scan.ErrExists) {
+			t.Errorf("Expected %s to be found, got %v\n", h, err)
+		}
+	}
+}
+
+func TestSaveAtomic(t *testing.T) {
+	dir := t.TempDir()
+	hostsFile := filepath.Join(dir, "pScan.hosts")
+	if err := os.WriteFile(hostsFile, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("host1")
+	if err := hl.Save(hostsFile); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the hosts file, got %d files\n", len(entries))
+	}
+
+	fi, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode %o to be kept, got %o instead\n", 0600, fi.Mode().Perm())
+	}
+}
+
+// largeList returns a list of n hosts
+func largeList(n int) *scan.HostsList {
+	hl := &scan.HostsList{}
+	for i := 0; i < n; i++ {
+		hl.Add(fmt.Sprintf("host%d.example.com", i))
+	}
+	return hl
+}
+
+func BenchmarkAdd100k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		largeList(100000)
+	}
+}
+
+func BenchmarkRemove100k(b *testing.B) {
+	hl := largeList(100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h := fmt.Sprintf("host%d.example.com", i%100000)
+		if err := hl.Remove(h); err != nil {
+			b.Fatal(err)
+		}
+		hl.Add(h)
+	}
+}
+
+func BenchmarkSave100k(b *testing.B) {
+	hl := largeList(100000)
+	hostsFile := filepath.Join(b.TempDir(), "pScan.hosts")
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := hl.Save(hostsFile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoad100k(b *testing.B) {
+	hostsFile := filepath.Join(b.TempDir(), "pScan.hosts")
+	if err := largeList(100000).Save(hostsFile); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		hl := &scan.HostsList{}
+		if err := hl.Load(hostsFile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	hl := &scan.HostsList{}
+	hl.AddWithInfo("web1", scan.HostInfo{Group: "web", Tags: []string{"prod", "eu"}})
+	hl.AddWithInfo("web2", scan.HostInfo{Group: "web", Tags: []string{"dev"}})
+	hl.Add("host3")
+
+	testCases := []struct {
+		name   string
+		group  string
+		tags   []string
+		expect []string
+	}{
+		{"All", "", nil, []string{"web1", "web2", "host3"}},
+		{"Group", "web", nil, []string{"web1", "web2"}},
+		{"Tag", "", []string{"prod"}, []string{"web1"}},
+		{"AllTags", "", []string{"prod", "dev"}, []string{}},
+		{"GroupAndTag", "web", []string{"dev"}, []string{"web2"}},
+		{"UnknownGroup", "db", nil, []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched := []string{}
+			for _, h := range hl.Hosts {
+				if hl.Match(h, tc.group, tc.tags) {
+					matched = append(matched, h)
+				}
+			}
+
+			if !reflect.DeepEqual(matched, tc.expect) {
+				t.Errorf("Expected hosts %q, got %q instead\n", tc.expect, matched)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	icmpEchoReply   = 0
+	icmpEchoRequest = 8
+)
+
+// pingHost sends the ICMP probes of Discover. Tests replace it to check
+// the TCP probes alone
+var pingHost = ping
+
+// icmpSeq numbers the echo requests so replies can be matched
+var icmpSeq uint32
+
+// ping sends an ICMP echo request to host and reports whether it
+// answered within timeout. Sending ICMP needs a raw socket, which
+// usually requires elevated privileges, so ping quietly reports false
+// when the system doesn't permit it
+func ping(ctx context.Context, host string, timeout time.Duration) bool {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "ip4:icmp", host)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	id := uint16(os.Getpid())
+	seq := uint16(atomic.AddUint32(&icmpSeq, 1))
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// stop waiting for the reply if ctx is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	if _, err := conn.Write(echoRequest(id, seq)); err != nil {
+		return false
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.(*net.IPConn).ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+
+		// the connection only receives packets from host, but they may
+		// be replies to other requests or other kinds of ICMP messages
+		msg := buf[:n]
+		if len(msg) >= 8 && msg[0] == icmpEchoReply &&
+			binary.BigEndian.Uint16(msg[4:]) == id &&
+			binary.BigEndian.Uint16(msg[6:]) == seq {
+			return true
+		}
+	}
+}
+
+// echoRequest builds an ICMP echo request message
+func echoRequest(id, seq uint16) []byte {
+	msg := []byte{icmpEchoRequest, 0, 0, 0, 0, 0, 0, 0, 'p', 'S', 'c', 'a', 'n'}
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	binary.BigEndian.PutUint16(msg[2:], checksum(msg))
+	return msg
+}
+
+// checksum computes the Internet checksum of RFC 1071
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,146 @@
+package scan
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// limiter spaces out events so that no more than rate of them happen
+// every second. A nil limiter doesn't limit anything
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next event is allowed or ctx is canceled
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// hostLimits holds the limits that apply to a single address
+type hostLimits struct {
+	rate  *limiter
+	slots chan struct{}
+}
+
+func newHostLimits(opts Options) *hostLimits {
+	h := &hostLimits{rate: newLimiter(opts.HostRate)}
+	if opts.MaxPerHost > 0 {
+		h.slots = make(chan struct{}, opts.MaxPerHost)
+	}
+	return h
+}
+
+// acquire takes one of the connection slots of the address. The slot
+// must be given back with release
+func (h *hostLimits) acquire(ctx context.Context) error {
+	if h.slots == nil {
+		return nil
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *hostLimits) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// interleave reorders order round-robin by the key of its elements,
+// keeping the relative order of the elements with the same key
+func interleave(order []int, key func(i int) int) []int {
+	groups := map[int][]int{}
+	keys := []int{}
+	for _, i := range order {
+		k := key(i)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	mixed := make([]int, 0, len(order))
+	for len(mixed) < len(order) {
+		for _, k := range keys {
+			if g := groups[k]; len(g) > 0 {
+				mixed = append(mixed, g[0])
+				groups[k] = g[1:]
+			}
+		}
+	}
+
+	return mixed
+}
+
+// rng is shared by the workers, so access is serialized. The global
+// source isn't used because it always starts from the same seed
+var rng = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// jitter waits for a random time up to max
+func jitter(ctx context.Context, max time.Duration) error {
+	if max <= 0 {
+		return nil
+	}
+
+	rng.Lock()
+	d := time.Duration(rng.Int63n(int64(max)))
+	rng.Unlock()
+
+	return sleep(ctx, d)
+}
+
+// shuffle randomizes the order of n elements using swap
+func shuffle(n int, swap func(i, j int)) {
+	rng.Lock()
+	defer rng.Unlock()
+	rng.Shuffle(n, swap)
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
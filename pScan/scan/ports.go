@@ -0,0 +1,230 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPorts = errors.New("Invalid port specification")
+
+// services maps well known TCP ports to their service name
+var services = map[int]string{
+	7:     "echo",
+	9:     "discard",
+	13:    "daytime",
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	26:    "rsftp",
+	37:    "time",
+	53:    "domain",
+	79:    "finger",
+	80:    "http",
+	81:    "hosts2-ns",
+	88:    "kerberos-sec",
+	106:   "pop3pw",
+	110:   "pop3",
+	111:   "rpcbind",
+	113:   "ident",
+	119:   "nntp",
+	135:   "msrpc",
+	139:   "netbios-ssn",
+	143:   "imap",
+	144:   "news",
+	179:   "bgp",
+	199:   "smux",
+	389:   "ldap",
+	427:   "svrloc",
+	443:   "https",
+	444:   "snpp",
+	445:   "microsoft-ds",
+	465:   "smtps",
+	513:   "login",
+	514:   "shell",
+	515:   "printer",
+	543:   "klogin",
+	544:   "kshell",
+	548:   "afp",
+	554:   "rtsp",
+	587:   "submission",
+	631:   "ipp",
+	646:   "ldp",
+	873:   "rsync",
+	990:   "ftps",
+	993:   "imaps",
+	995:   "pop3s",
+	1025:  "nfs-or-iis",
+	1026:  "lsa-or-nterm",
+	1027:  "iis",
+	1029:  "ms-lsa",
+	1110:  "nfsd-status",
+	1433:  "ms-sql-s",
+	1521:  "oracle",
+	1720:  "h323q931",
+	1723:  "pptp",
+	1755:  "wms",
+	1900:  "upnp",
+	2000:  "cisco-sccp",
+	2001:  "dc",
+	2049:  "nfs",
+	2121:  "ccproxy-ftp",
+	2717:  "pn-requester",
+	3000:  "ppp",
+	3128:  "squid-http",
+	3306:  "mysql",
+	3389:  "ms-wbt-server",
+	3986:  "mapper-ws-ethd",
+	4899:  "radmin",
+	5000:  "upnp",
+	5009:  "airport-admin",
+	5051:  "ida-agent",
+	5060:  "sip",
+	5101:  "admdog",
+	5190:  "aol",
+	5357:  "wsdapi",
+	5432:  "postgresql",
+	5631:  "pcanywheredata",
+	5666:  "nrpe",
+	5800:  "vnc-http",
+	5900:  "vnc",
+	5984:  "couchdb",
+	6000:  "x11",
+	6001:  "x11-1",
+	6379:  "redis",
+	7070:  "realserver",
+	8000:  "http-alt",
+	8008:  "http",
+	8009:  "ajp13",
+	8080:  "http-proxy",
+	8081:  "blackice-icecap",
+	8443:  "https-alt",
+	8888:  "sun-answerbook",
+	9042:  "cassandra",
+	9100:  "jetdirect",
+	9200:  "elasticsearch",
+	9999:  "abyss",
+	10000: "snet-sensor-mgmt",
+	11211: "memcache",
+	27017: "mongodb",
+}
+
+// portSets are the named groups of ports accepted by ParsePorts
+var portSets = map[string][]int{
+	// the 100 most common TCP ports, as used by nmap
+	"top100": {
+		7, 9, 13, 21, 22, 23, 25, 26, 37, 53, 79, 80, 81, 88, 106, 110,
+		111, 113, 119, 135, 139, 143, 144, 179, 199, 389, 427, 443, 444,
+		445, 465, 513, 514, 515, 543, 544, 548, 554, 587, 631, 646, 873,
+		990, 993, 995, 1025, 1026, 1027, 1028, 1029, 1110, 1433, 1720,
+		1723, 1755, 1900, 2000, 2001, 2049, 2121, 2717, 3000, 3128, 3306,
+		3389, 3986, 4899, 5000, 5009, 5051, 5060, 5101, 5190, 5357, 5432,
+		5631, 5666, 5800, 5900, 6000, 6001, 6646, 7070, 8000, 8008, 8009,
+		8080, 8081, 8443, 8888, 9100, 9999, 10000, 32768, 49152, 49153,
+		49154, 49155, 49156, 49157,
+	},
+	"web": {80, 443, 8000, 8008, 8080, 8081, 8443, 8888},
+	"db":  {1433, 1521, 3306, 5432, 5984, 6379, 9042, 9200, 11211, 27017},
+}
+
+// Service returns the name of the service usually found on a TCP or UDP
+// port, or an empty string if the port is not well known
+func Service(proto string, port int) string {
+	if proto == UDP {
+		return udpServices[port]
+	}
+	return services[port]
+}
+
+// ParsePorts expands a list of port specifications into ports to scan.
+// Each entry is a port (80), a range (1-1024) or a named set (top100,
+// web, db). Entries starting with ! are excluded from the result.
+// Ports are returned in the order they are first given, without
+// duplicates
+func ParsePorts(specs []string) ([]int, error) {
+	include := []int{}
+	exclude := map[int]bool{}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		excluded := strings.HasPrefix(spec, "!")
+		ports, err := expandPorts(strings.TrimPrefix(spec, "!"))
+		if err != nil {
+			return nil, err
+		}
+
+		if !excluded {
+			include = append(include, ports...)
+			continue
+		}
+		for _, p := range ports {
+			exclude[p] = true
+		}
+	}
+
+	ports := []int{}
+	seen := map[int]bool{}
+	for _, p := range include {
+		if exclude[p] || seen[p] {
+			continue
+		}
+		seen[p] = true
+		ports = append(ports, p)
+	}
+
+	return ports, nil
+}
+
+// expandPorts converts a single port, range or set name into ports
+func expandPorts(spec string) ([]int, error) {
+	if set, ok := portSets[strings.ToLower(spec)]; ok {
+		return set, nil
+	}
+
+	first, last, isRange := strings.Cut(spec, "-")
+
+	start, err := parsePort(first)
+	if err != nil {
+		return nil, err
+	}
+
+	end := start
+	if isRange {
+		if end, err = parsePort(last); err != nil {
+			return nil, err
+		}
+	}
+
+	if end < start {
+		return nil, fmt.Errorf("%w: range %s ends before it starts",
+			ErrInvalidPorts, spec)
+	}
+
+	ports := make([]int, 0, end-start+1)
+	for p := start; p <= end; p++ {
+		ports = append(ports, p)
+	}
+
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a port, range or set name",
+			ErrInvalidPorts, s)
+	}
+
+	if p < 1 || p > 65535 {
+		return 0, fmt.Errorf("%w: port %d out of range 1-65535",
+			ErrInvalidPorts, p)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,87 @@
+package scan_test
+
+import (
+	"errors"
+	"pScan/scan"
+	"reflect"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		specs     []string
+		expectLen int
+		expect    []int
+		expectErr error
+	}{
+		{name: "Single", specs: []string{"22", "80"}, expect: []int{22, 80}},
+		{name: "Range", specs: []string{"20-23"}, expect: []int{20, 21, 22, 23}},
+		{name: "Set", specs: []string{"web"},
+			expect: []int{80, 443, 8000, 8008, 8080, 8081, 8443, 8888}},
+		{name: "SetSize", specs: []string{"top100"}, expectLen: 100},
+		{name: "Exclude", specs: []string{"20-25", "!22", "!24-25"},
+			expect: []int{20, 21, 23}},
+		{name: "ExcludeSet", specs: []string{"80-90", "!web"},
+			expect: []int{81, 82, 83, 84, 85, 86, 87, 88, 89, 90}},
+		{name: "Dedupe", specs: []string{"443", "web", "80"},
+			expect: []int{443, 80, 8000, 8008, 8080, 8081, 8443, 8888}},
+		{name: "Empty", specs: []string{}, expect: []int{}},
+		{name: "NotANumber", specs: []string{"http"}, expectErr: scan.ErrInvalidPorts},
+		{name: "OutOfRange", specs: []string{"65536"}, expectErr: scan.ErrInvalidPorts},
+		{name: "Zero", specs: []string{"0-10"}, expectErr: scan.ErrInvalidPorts},
+		{name: "Reversed", specs: []string{"100-10"}, expectErr: scan.ErrInvalidPorts},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports, err := scan.ParsePorts(tc.specs)
+
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("Expected error %q, got %q instead\n", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %q instead\n", err)
+			}
+
+			if tc.expect == nil {
+				if len(ports) != tc.expectLen {
+					t.Errorf("Expected %d ports, got %d instead\n", tc.expectLen, len(ports))
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(ports, tc.expect) {
+				t.Errorf("Expected ports %v, got %v instead\n", tc.expect, ports)
+			}
+		})
+	}
+}
+
+func TestService(t *testing.T) {
+	testCases := []struct {
+		proto  string
+		port   int
+		expect string
+	}{
+		{scan.TCP, 22, "ssh"},
+		{scan.TCP, 443, "https"},
+		{scan.TCP, 5432, "postgresql"},
+		{scan.TCP, 40000, ""},
+		{scan.TCP, 123, ""},
+		{scan.UDP, 123, "ntp"},
+		{scan.UDP, 161, "snmp"},
+		{scan.UDP, 22, ""},
+	}
+
+	for _, tc := range testCases {
+		if s := scan.Service(tc.proto, tc.port); s != tc.expect {
+			t.Errorf("Expected service %q for %s port %d, got %q instead\n",
+				tc.expect, tc.proto, tc.port, s)
+		}
+	}
+}
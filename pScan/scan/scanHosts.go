@@ -1,77 +1,613 @@
 package scan
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"sync"
+	"syscall"
 	"time"
 )
 
-// PortState represets the state of a single TCP port
+// Protocols accepted by Run
+const (
+	TCP = "tcp"
+	UDP = "udp"
+)
+
+// Address families accepted by Run
+const (
+	IPv4 = "ip4"
+	IPv6 = "ip6"
+)
+
+// PortState represets the state of a single TCP or UDP port
 type PortState struct {
-	Port int
-	Open state
+	Port        int           `json:"port"`
+	Proto       string        `json:"proto"`
+	State       state         `json:"state"`
+	Latency     time.Duration `json:"latency"`
+	Fingerprint *Fingerprint  `json:"fingerprint,omitempty"`
+	TLS         *TLSInfo      `json:"tls,omitempty"`
 }
 
-type state bool
+type state int
+
+// States a port can be in. Filtered is used for TCP ports that didn't
+// answer or were reported unreachable, usually because of a firewall.
+// OpenFiltered is used for UDP ports that didn't answer the probe, since
+// that can mean either the service ignored it or a firewall dropped it
+const (
+	StateClosed state = iota
+	StateOpen
+	StateFiltered
+	StateOpenFiltered
+)
 
-// String converts the boolean value of state to a human readable string
+// String converts the value of state to a human readable string
 func (s state) String() string {
-	if s {
+	switch s {
+	case StateOpen:
 		return "open"
+	case StateFiltered:
+		return "filtered"
+	case StateOpenFiltered:
+		return "open|filtered"
 	}
 	return "closed"
 }
 
-// scanPort performs a port scan on a single TCP port
-func scanPort(host string, port int) PortState {
+// MarshalText stores the state by name so saved results stay readable
+func (s state) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a state saved with MarshalText
+func (s *state) UnmarshalText(b []byte) error {
+	for _, st := range []state{StateClosed, StateOpen, StateFiltered, StateOpenFiltered} {
+		if st.String() == string(b) {
+			*s = st
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown port state %q", b)
+}
+
+// DefaultTimeout is how long Run waits for each port when no timeout
+// is given in its options
+const DefaultTimeout = 1 * time.Second
+
+// Options control how Run scans the hosts list
+type Options struct {
+	// Proto is either TCP or UDP. It defaults to TCP
+	Proto string
+	// Family limits the scan to the IPv4 or IPv6 addresses of the
+	// hosts. Both are scanned when it is empty
+	Family string
+	// Workers is the number of ports scanned concurrently
+	Workers int
+	// Timeout is how long to wait for each port. It defaults to
+	// DefaultTimeout
+	Timeout time.Duration
+	// Banners reads the banner of open TCP ports to identify the
+	// service behind them
+	Banners bool
+	// TLS runs a TLS handshake on open TCP ports and records the
+	// session and certificate details of those that speak TLS
+	TLS bool
+	// Group and Tags limit the scan to the hosts of the list in Group
+	// and tagged with all of Tags
+	Group string
+	Tags  []string
+	// SkipDown sends the probes of Discover to every host before
+	// scanning it, and skips the hosts that don't answer
+	SkipDown bool
+	// DiscoveryPorts are the ports probed by Discover. They default to
+	// DiscoveryPorts
+	DiscoveryPorts []int
+	// Resolver resolves the hosts. Scans sharing a Resolver share its
+	// cache. Each scan gets its own zero Resolver if nil
+	Resolver *Resolver
+	// ReverseDNS looks up the names of the hosts given as IP addresses,
+	// including those of CIDR blocks and ranges, and reports them in
+	// Results.Names
+	ReverseDNS bool
+	// Rate limits the connections of the whole scan to Rate every
+	// second, and HostRate those to a single address. MaxPerHost caps
+	// the connections open to a single address at the same time. They
+	// count the discovery probes of SkipDown and the TLS handshakes too.
+	// Zero values mean no limit
+	Rate       float64
+	HostRate   float64
+	MaxPerHost int
+	// Randomize scans the ports in random order, mixing the addresses
+	// as well. Results are still sorted as without it
+	Randomize bool
+	// Jitter waits for a random time up to Jitter before every
+	// connection
+	Jitter time.Duration
+	// OnPort is called with the result of every port as soon as it is
+	// scanned. Calls are never concurrent
+	OnPort func(PortResult)
+}
+
+// network returns the network to dial for proto, limited to
+// o.Family if set
+func (o Options) network(proto string) string {
+	switch o.Family {
+	case IPv4:
+		return proto + "4"
+	case IPv6:
+		return proto + "6"
+	}
+	return proto
+}
+
+// PortResult is the result of a single port, reported while the scan
+// runs. Done and Total count the ports scanned so far and the ports the
+// whole scan will check
+type PortResult struct {
+	Host    string
+	Address string
+	PortState
+	Done  int
+	Total int
+}
+
+// dialTimeout opens the connections used to check ports. Tests replace
+// it to simulate network latency
+var dialTimeout = func(ctx context.Context, network, address string,
+	timeout time.Duration) (net.Conn, error) {
+
+	d := net.Dialer{Timeout: timeout}
+	return d.DialContext(ctx, network, address)
+}
+
+// scanPort performs a port scan on a single TCP port of addr, one of
+// the addresses of host. The TLS handshake asked for by opts.TLS is a
+// connection of its own, which RunContext makes after this one closes
+func scanPort(ctx context.Context, host, addr string, port int, opts Options) PortState {
 	p := PortState{
-		Port: port,
+		Port:  port,
+		Proto: TCP,
+	}
+
+	address := net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+	start := time.Now()
+	scanConn, err := dialTimeout(ctx, "tcp", address, opts.Timeout)
+	p.Latency = time.Since(start)
+
+	p.State = dialState(err)
+	if err != nil {
+		return p
+	}
+	defer scanConn.Close()
+
+	if opts.Banners {
+		p.Fingerprint = grabBanner(scanConn, host, port, opts.Timeout)
+	}
+
+	return p
+}
+
+// dialState classifies the result of a TCP connect. A refused
+// connection means the host answered with a reset, so the port is
+// closed. Timeouts and ICMP unreachable errors mean something dropped
+// or rejected the connection before it reached the host
+func dialState(err error) state {
+	if err == nil {
+		return StateOpen
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StateClosed
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return StateFiltered
 	}
 
-	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	scanConn, err := net.DialTimeout("tcp", address, 1*time.Second)
+	return StateClosed
+}
 
-	// if there is an error, the port is closed
+// scanUDPPort sends a probe to a single UDP port and waits for an
+// answer. Any answer means the port is open. An ICMP port unreachable,
+// which the kernel reports as a refused connection on the next read,
+// means it is closed. Without an answer the port is open|filtered
+func scanUDPPort(ctx context.Context, host, addr string, port int, opts Options) PortState {
+	p := PortState{
+		Port:  port,
+		Proto: UDP,
+	}
+
+	address := net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+	start := time.Now()
+	conn, err := dialTimeout(ctx, "udp", address, opts.Timeout)
 	if err != nil {
+		p.Latency = time.Since(start)
 		return p
 	}
+	defer conn.Close()
+
+	buf := make([]byte, 512)
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+	_, err = conn.Write(udpProbe(port))
+	if err == nil {
+		_, err = conn.Read(buf)
+	}
+	p.Latency = time.Since(start)
+
+	switch {
+	case err == nil:
+		p.State = StateOpen
+	case errors.Is(err, syscall.ECONNREFUSED):
+		p.State = StateClosed
+	default:
+		p.State = StateOpenFiltered
+	}
 
-	scanConn.Close()
-	p.Open = true
 	return p
 }
 
-// Results represents the scan results for a single host
+// Results represents the scan results for a single address of a host.
+// Hosts with several addresses have one Results for each of them, and
+// Addresses lists all of them. Down is set for addresses skipped because
+// they didn't answer the discovery probes. Names lists the names the
+// address resolves back to, for hosts given as IP addresses when
+// reverse lookups are enabled
 type Results struct {
-	Host       string
-	NotFound   bool
-	PortStates []PortState
+	Host       string      `json:"host"`
+	Address    string      `json:"address,omitempty"`
+	NotFound   bool        `json:"not_found,omitempty"`
+	Down       bool        `json:"down,omitempty"`
+	Addresses  []string    `json:"addresses,omitempty"`
+	Names      []string    `json:"names,omitempty"`
+	PortStates []PortState `json:"ports,omitempty"`
 }
 
-// Run performs a port scan on the hosts list
-func Run(hl *HostsList, ports []int) []Results {
-	res := make([]Results, 0, len(hl.Hosts))
+// Run performs a port scan on the hosts list. It is RunContext without
+// a way to stop the scan
+func Run(hl *HostsList, ports []int, opts Options) []Results {
+	res, _ := RunContext(context.Background(), hl, ports, opts)
+	return res
+}
 
-	/*
-		Loop thorugh list of hosts and define an instance of Results
-		for each host
-	*/
-	for _, h := range hl.Hosts {
-		r := Results{
-			Host: h,
+// Name identifies the scanned address in reports. It is the host name,
+// followed by the address in parentheses when the host has several
+func (r Results) Name() string {
+	if len(r.Addresses) < 2 || r.Address == r.Host {
+		return r.Host
+	}
+	return fmt.Sprintf("%s (%s)", r.Host, r.Address)
+}
+
+// RunContext performs a port scan on the hosts list using up to
+// opts.Workers concurrent connections. Every address a host resolves to
+// is scanned, limited to opts.Family if set. Hosts with their own ports
+// in the list are scanned on those instead of ports. CIDR blocks and
+// ranges in the list are scanned address by address. Results keep the
+// order of the hosts list, of the resolved addresses and of the ports.
+//
+// If ctx is canceled the scan stops and RunContext returns the results
+// gathered so far along with the context error. Hosts that weren't
+// resolved and ports that weren't scanned are left out of them
+func RunContext(ctx context.Context, hl *HostsList, ports []int,
+	opts Options) ([]Results, error) {
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = &Resolver{}
+	}
+
+	hosts := targets(hl, ports, opts)
+	addrs := make([][]string, len(hosts))
+	resolved := make([]bool, len(hosts))
+
+	// resolve every name first so only the ones found are scanned.
+	// Addresses need no lookup
+	parallel(ctx, len(hosts), opts.Workers, func(i int) {
+		if hosts[i].first.IsValid() {
+			resolved[i] = true
+			return
+		}
+
+		a, err := opts.Resolver.LookupHost(ctx, hosts[i].host)
+		if ctx.Err() != nil {
+			return
+		}
+		resolved[i] = true
+		if err == nil {
+			addrs[i] = filterFamily(a, opts.Family)
+		}
+	})
+
+	// each address of a host gets its own results
+	res := []Results{}
+	resPorts := [][]int{}
+	addHost := func(host string, addrs []string, ports []int) {
+		if len(addrs) == 0 {
+			res = append(res, Results{Host: host, NotFound: true})
+			resPorts = append(resPorts, nil)
+			return
+		}
+
+		for _, a := range addrs {
+			res = append(res, Results{Host: host, Address: a, Addresses: addrs})
+			resPorts = append(resPorts, ports)
+		}
+	}
+
+	for i, h := range hosts {
+		switch {
+		case !resolved[i]:
+		case h.first.IsValid():
+			h.each(func(host string) {
+				addHost(host, filterFamily([]string{host}, opts.Family), h.ports)
+			})
+		default:
+			addHost(h.host, addrs[i], h.ports)
+		}
+	}
+
+	// discovery probes, port scans and TLS handshakes all count against
+	// the same limits
+	rate := newLimiter(opts.Rate)
+	limits := make([]*hostLimits, len(res))
+	for i := range limits {
+		limits[i] = newHostLimits(opts)
+	}
+
+	probed := make([]bool, len(res))
+	parallel(ctx, len(res), opts.Workers, func(i int) {
+		if opts.ReverseDNS && res[i].Address == res[i].Host {
+			// names that can't be found are simply not reported
+			res[i].Names, _ = opts.Resolver.LookupAddr(ctx, res[i].Address)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		if opts.SkipDown && !res[i].NotFound {
+			up, _, _ := probeHost(ctx, res[i].Address, opts, limits[i], rate)
+			if ctx.Err() != nil {
+				return
+			}
+			res[i].Down = !up
+		}
+		probed[i] = true
+	})
+
+	// each job scans a single port on a single address and stores the
+	// result in its own slot, so no locking is needed
+	type job struct {
+		res, port int
+		done      bool
+	}
+
+	jobs := []job{}
+	for r := range res {
+		if !probed[r] || res[r].NotFound || res[r].Down {
+			continue
+		}
+		res[r].PortStates = make([]PortState, len(resPorts[r]))
+		for p := range resPorts[r] {
+			jobs = append(jobs, job{res: r, port: p})
+		}
+	}
+
+	order := make([]int, len(jobs))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.Randomize {
+		shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+	if opts.MaxPerHost > 0 {
+		// workers handed several jobs of the same address in a row would
+		// all wait for its slots while the other addresses sit idle
+		order = interleave(order, func(i int) int { return jobs[i].res })
+	}
+
+	scanFn := scanPort
+	if opts.Proto == UDP {
+		scanFn = scanUDPPort
+	}
+
+	mu := sync.Mutex{}
+	done := 0
+
+	parallel(ctx, len(jobs), opts.Workers, func(i int) {
+		j := &jobs[order[i]]
+		r := &res[j.res]
+		var p PortState
+		err := limited(ctx, limits[j.res], rate, opts, func() {
+			p = scanFn(ctx, r.Host, r.Address, resPorts[j.res][j.port], opts)
+		})
+		if err == nil && opts.TLS && p.Proto == TCP && p.State == StateOpen {
+			err = limited(ctx, limits[j.res], rate, opts, func() {
+				p.TLS = inspectTLS(ctx, r.Host, r.Address, p.Port, opts.Timeout)
+			})
+		}
+		// a dial interrupted by the context says nothing about the port
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+		r.PortStates[j.port] = p
+		j.done = true
+
+		if opts.OnPort == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		opts.OnPort(PortResult{Host: r.Host, Address: r.Address, PortState: p,
+			Done: done, Total: len(jobs)})
+	})
+
+	if ctx.Err() == nil {
+		return res, nil
+	}
+
+	// drop what the scan didn't get to
+	scanned := map[int][]PortState{}
+	for _, j := range jobs {
+		if j.done {
+			scanned[j.res] = append(scanned[j.res], res[j.res].PortStates[j.port])
+		}
+	}
+
+	partial := []Results{}
+	for i, r := range res {
+		if !probed[i] {
+			continue
+		}
+		if !r.NotFound && !r.Down {
+			r.PortStates = scanned[i]
+		}
+		partial = append(partial, r)
+	}
+
+	return partial, ctx.Err()
+}
+
+// limited runs fn once the limits of the scan and of the address allow
+// another connection
+func limited(ctx context.Context, host *hostLimits, rate *limiter,
+	opts Options, fn func()) error {
+
+	if err := host.acquire(ctx); err != nil {
+		return err
+	}
+	defer host.release()
+
+	if err := jitter(ctx, opts.Jitter); err != nil {
+		return err
+	}
+	if err := host.rate.wait(ctx); err != nil {
+		return err
+	}
+	if err := rate.wait(ctx); err != nil {
+		return err
+	}
+
+	fn()
+	return nil
+}
+
+// filterFamily keeps the addresses of the given family. All addresses
+// are kept when family is empty
+func filterFamily(addrs []string, family string) []string {
+	if family == "" {
+		return addrs
+	}
+
+	kept := []string{}
+	for _, a := range addrs {
+		ip, err := netip.ParseAddr(a)
+		if err != nil {
+			continue
+		}
+		if ip.Unmap().Is4() == (family == IPv4) {
+			kept = append(kept, a)
 		}
-		if _, err := net.LookupHost(h); err != nil {
-			r.NotFound = true
-			res = append(res, r)
+	}
+
+	return kept
+}
+
+// target is an entry of the hosts list to scan along with its ports.
+// CIDR blocks and ranges keep their first and last address only, and
+// are walked address by address when scanned
+type target struct {
+	host        string
+	first, last netip.Addr
+	ports       []int
+}
+
+// each calls fn with every host the target covers
+func (t target) each(fn func(host string)) {
+	if !t.first.IsValid() {
+		fn(t.host)
+		return
+	}
+
+	for a := t.first; ; a = a.Next() {
+		fn(a.String())
+		if a == t.last {
+			break
+		}
+	}
+}
+
+// targets returns the entries of the hosts list that match the group
+// and tags in opts. Entries that aren't valid are kept as names and
+// reported as not found
+func targets(hl *HostsList, ports []int, opts Options) []target {
+	hosts := []target{}
+
+	for _, h := range hl.Hosts {
+		if !hl.Match(h, opts.Group, opts.Tags) {
 			continue
 		}
 
-		// if the host is found, loop through the port slice and scan each port
-		// for the host
-		for _, p := range ports {
-			r.PortStates = append(r.PortStates, scanPort(h, p))
+		hostPorts := ports
+		if specs := hl.Info[h].Ports; len(specs) > 0 {
+			// the ports were checked when the list was loaded
+			hostPorts, _ = ParsePorts(specs)
 		}
-		res = append(res, r)
+
+		first, last, err := parseEntry(h)
+		if err != nil {
+			first, last = netip.Addr{}, netip.Addr{}
+		}
+
+		hosts = append(hosts, target{host: h, first: first, last: last, ports: hostPorts})
 	}
-	return res
+
+	return hosts
+}
+
+// parallel calls fn for every index from 0 to n-1 using at most workers
+// goroutines, and returns once all calls are done. No new calls are
+// made once ctx is canceled
+func parallel(ctx context.Context, n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	idx := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				fn(i)
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case idx <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(idx)
+
+	wg.Wait()
 }
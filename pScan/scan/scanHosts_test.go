@@ -1,23 +1,64 @@
 package scan_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
+	"os"
 	"pScan/scan"
+	"reflect"
 	"strconv"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestStateString(t *testing.T) {
 	ps := scan.PortState{}
 
-	if ps.Open.String() != "closed" {
-		t.Errorf("Expected %q, got %q instead\n", "closed", ps.Open.String())
+	if ps.State.String() != "closed" {
+		t.Errorf("Expected %q, got %q instead\n", "closed", ps.State.String())
 	}
 
-	ps.Open = true
+	ps.State = scan.StateOpen
 
-	if ps.Open.String() != "open" {
-		t.Errorf("Expected %q, got %q instead\n", "open", ps.Open.String())
+	if ps.State.String() != "open" {
+		t.Errorf("Expected %q, got %q instead\n", "open", ps.State.String())
+	}
+
+	ps.State = scan.StateOpenFiltered
+
+	if ps.State.String() != "open|filtered" {
+		t.Errorf("Expected %q, got %q instead\n", "open|filtered", ps.State.String())
+	}
+}
+
+func TestDialState(t *testing.T) {
+	dialErr := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp",
+			Err: os.NewSyscallError("connect", err)}
+	}
+
+	testCases := []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{"Connected", nil, "open"},
+		{"Refused", dialErr(syscall.ECONNREFUSED), "closed"},
+		{"Timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, "filtered"},
+		{"HostUnreachable", dialErr(syscall.EHOSTUNREACH), "filtered"},
+		{"NetUnreachable", dialErr(syscall.ENETUNREACH), "filtered"},
+		{"Other", errors.New("connection failed"), "closed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := scan.DialState(tc.err); s != tc.expect {
+				t.Errorf("Expected state %q, got %q instead\n", tc.expect, s)
+			}
+		})
 	}
 }
 
@@ -61,7 +102,7 @@ func TestRunHostFound(t *testing.T) {
 			ln.Close()
 		}
 	}
-	res := scan.Run(hl, ports)
+	res := scan.Run(hl, ports, scan.Options{Workers: 10, Family: scan.IPv4})
 
 	// Verify results for HostFound test
 	if len(res) != 1 {
@@ -85,9 +126,12 @@ func TestRunHostFound(t *testing.T) {
 			t.Errorf("Expected port %d, got %d instead\n", ports[0],
 				res[0].PortStates[i].Port)
 		}
-		if res[0].PortStates[i].Open.String() != tc.expectState {
+		if res[0].PortStates[i].State.String() != tc.expectState {
 			t.Errorf("Expected port %d to be %s", ports[i], tc.expectState)
 		}
+		if res[0].PortStates[i].Latency <= 0 {
+			t.Errorf("Expected latency to be recorded for port %d", ports[i])
+		}
 	}
 }
 
@@ -97,7 +141,7 @@ func TestRunHostNotFound(t *testing.T) {
 
 	hl.Add(host)
 
-	res := scan.Run(hl, []int{})
+	res := scan.Run(hl, []int{}, scan.Options{Workers: 10})
 
 	// Verify results for HostNotFound test
 	if len(res) != 1 {
@@ -116,3 +160,508 @@ func TestRunHostNotFound(t *testing.T) {
 		t.Fatalf("Expected 0 port states, got %d instead\n", len(res[0].PortStates))
 	}
 }
+
+// listenPorts starts n listeners on localhost and closes every other one,
+// so the returned ports alternate between open and closed
+func listenPorts(t testing.TB, n int) []int {
+	t.Helper()
+
+	ports := []int{}
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i%2 == 0 {
+			t.Cleanup(func() { ln.Close() })
+		} else {
+			ln.Close()
+		}
+
+		ports = append(ports, ln.Addr().(*net.TCPAddr).Port)
+	}
+
+	return ports
+}
+
+func TestRunWorkersOrder(t *testing.T) {
+	ports := listenPorts(t, 20)
+
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+	hl.Add("389.389.389.389")
+
+	for _, workers := range []int{0, 1, 7, 100} {
+		t.Run(fmt.Sprintf("Workers%d", workers), func(t *testing.T) {
+			res := scan.Run(hl, ports, scan.Options{Workers: workers, Family: scan.IPv4})
+
+			if len(res) != 2 {
+				t.Fatalf("Expected 2 results, got %d instead\n", len(res))
+			}
+
+			for i, h := range hl.Hosts {
+				if res[i].Host != h {
+					t.Fatalf("Expected host %q at index %d, got %q instead\n",
+						h, i, res[i].Host)
+				}
+			}
+
+			if res[0].NotFound || !res[1].NotFound {
+				t.Fatalf("Expected only %q to be found\n", "localhost")
+			}
+
+			for i, p := range res[0].PortStates {
+				if p.Port != ports[i] {
+					t.Errorf("Expected port %d at index %d, got %d instead\n",
+						ports[i], i, p.Port)
+				}
+				if expOpen := i%2 == 0; (p.State == scan.StateOpen) != expOpen {
+					t.Errorf("Expected port %d open to be %t\n", p.Port, expOpen)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkRun(b *testing.B, latency time.Duration) {
+	ports := listenPorts(b, 200)
+
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+
+	defer scan.SetDialLatency(latency)()
+
+	for _, workers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("Workers%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scan.Run(hl, ports, scan.Options{Workers: workers, Family: scan.IPv4})
+			}
+		})
+	}
+}
+
+// BenchmarkRun scans local listeners. Connections on the loopback
+// interface are almost instant, so this measures the scanner overhead
+func BenchmarkRun(b *testing.B) {
+	benchmarkRun(b, 0)
+}
+
+// BenchmarkRunLatency adds a delay to every connection to show the
+// effect of the worker pool on a real network
+func BenchmarkRunLatency(b *testing.B) {
+	benchmarkRun(b, 2*time.Millisecond)
+}
+
+func TestRunRange(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	hl := &scan.HostsList{}
+	if err := hl.Add("127.0.0.0/30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hl.Add("127.0.0.5-6"); err != nil {
+		t.Fatal(err)
+	}
+
+	res := scan.Run(hl, []int{port}, scan.Options{Workers: 10})
+
+	expHosts := []string{"127.0.0.1", "127.0.0.2", "127.0.0.5", "127.0.0.6"}
+	if len(res) != len(expHosts) {
+		t.Fatalf("Expected %d results, got %d instead\n", len(expHosts), len(res))
+	}
+
+	for i, h := range expHosts {
+		if res[i].Host != h {
+			t.Errorf("Expected host %q, got %q instead\n", h, res[i].Host)
+		}
+		if res[i].NotFound {
+			t.Errorf("Expected host %q to be found\n", h)
+		}
+	}
+
+	// only the address the listener is bound to is open
+	if res[0].PortStates[0].State != scan.StateOpen ||
+		res[1].PortStates[0].State != scan.StateClosed {
+		t.Errorf("Expected port %d open on 127.0.0.1 only\n", port)
+	}
+
+	// addresses outside the family are reported one by one
+	res = scan.Run(hl, []int{port}, scan.Options{Workers: 10, Family: scan.IPv6})
+	if len(res) != len(expHosts) {
+		t.Fatalf("Expected %d results, got %d instead\n", len(expHosts), len(res))
+	}
+	for i, h := range expHosts {
+		if res[i].Host != h || !res[i].NotFound {
+			t.Errorf("Expected host %q not found, got %+v instead\n", h, res[i])
+		}
+	}
+}
+
+func TestRunFamily(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	defer scan.SetLookup(map[string][]string{
+		"dualhost": {"127.0.0.1", "::1"},
+		"v4host":   {"127.0.0.1"},
+	})()
+
+	testCases := []struct {
+		name        string
+		host        string
+		family      string
+		expectNames []string
+		expectAddrs []string
+	}{
+		{"Both", "dualhost", "",
+			[]string{"dualhost (127.0.0.1)", "dualhost (::1)"},
+			[]string{"127.0.0.1", "::1"}},
+		{"IPv4", "dualhost", scan.IPv4, []string{"dualhost"}, []string{"127.0.0.1"}},
+		{"IPv6", "dualhost", scan.IPv6, []string{"dualhost"}, []string{"::1"}},
+		{"Literal", "::1", "", []string{"::1"}, []string{"::1"}},
+		{"NoAddress", "v4host", scan.IPv6, []string{"v4host"}, []string{""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := &scan.HostsList{}
+			if err := hl.Add(tc.host); err != nil {
+				t.Fatal(err)
+			}
+
+			res := scan.Run(hl, []int{port}, scan.Options{Workers: 10, Family: tc.family})
+
+			if len(res) != len(tc.expectNames) {
+				t.Fatalf("Expected %d results, got %d instead\n", len(tc.expectNames), len(res))
+			}
+
+			for i, r := range res {
+				if r.Name() != tc.expectNames[i] {
+					t.Errorf("Expected name %q, got %q instead\n", tc.expectNames[i], r.Name())
+				}
+				if r.Address != tc.expectAddrs[i] {
+					t.Errorf("Expected address %q, got %q instead\n", tc.expectAddrs[i], r.Address)
+				}
+
+				if r.Address == "" {
+					if !r.NotFound {
+						t.Errorf("Expected host %q not found\n", tc.host)
+					}
+					continue
+				}
+
+				if len(r.PortStates) != 1 || r.PortStates[0].State != scan.StateOpen {
+					t.Errorf("Expected port %d open on %s, got %+v instead\n",
+						port, r.Address, r.PortStates)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUDP(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expectState string
+	}{
+		{"Answers", "open"},
+		{"Closed", "closed"},
+		{"Silent", "open|filtered"},
+	}
+
+	ports := []int{}
+
+	for _, tc := range testCases {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		ports = append(ports, conn.LocalAddr().(*net.UDPAddr).Port)
+
+		switch tc.name {
+		case "Answers":
+			go func() {
+				buf := make([]byte, 512)
+				_, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				conn.WriteTo([]byte("hello"), addr)
+			}()
+		case "Closed":
+			conn.Close()
+		}
+	}
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	res := scan.Run(hl, ports, scan.Options{
+		Proto:   scan.UDP,
+		Workers: 10,
+		Timeout: 200 * time.Millisecond,
+	})
+
+	if len(res) != 1 || len(res[0].PortStates) != len(testCases) {
+		t.Fatalf("Expected 1 host with %d ports, got %v instead\n",
+			len(testCases), res)
+	}
+
+	for i, tc := range testCases {
+		p := res[0].PortStates[i]
+		if p.Proto != scan.UDP {
+			t.Errorf("%s: expected protocol %q, got %q instead\n",
+				tc.name, scan.UDP, p.Proto)
+		}
+		if p.State.String() != tc.expectState {
+			t.Errorf("%s: expected port %d to be %s, got %s instead\n",
+				tc.name, p.Port, tc.expectState, p.State)
+		}
+	}
+}
+
+func TestRunHostInfo(t *testing.T) {
+	ports := listenPorts(t, 4)
+
+	hl := &scan.HostsList{}
+	hl.AddWithInfo("127.0.0.1", scan.HostInfo{
+		Group: "web",
+		Ports: []string{strconv.Itoa(ports[2])},
+	})
+	hl.AddWithInfo("localhost", scan.HostInfo{Group: "db", Tags: []string{"prod"}})
+
+	testCases := []struct {
+		name        string
+		group       string
+		tags        []string
+		expectHosts []string
+		expectPorts [][]int
+	}{
+		{"All", "", nil, []string{"127.0.0.1", "localhost"},
+			[][]int{{ports[2]}, {ports[0], ports[1]}}},
+		{"Group", "web", nil, []string{"127.0.0.1"}, [][]int{{ports[2]}}},
+		{"Tag", "", []string{"prod"}, []string{"localhost"},
+			[][]int{{ports[0], ports[1]}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := scan.Run(hl, ports[:2], scan.Options{
+				Workers: 10,
+				Family:  scan.IPv4,
+				Group:   tc.group,
+				Tags:    tc.tags,
+			})
+
+			if len(res) != len(tc.expectHosts) {
+				t.Fatalf("Expected %d results, got %d instead\n",
+					len(tc.expectHosts), len(res))
+			}
+
+			for i, r := range res {
+				if r.Host != tc.expectHosts[i] {
+					t.Errorf("Expected host %q, got %q instead\n", tc.expectHosts[i], r.Host)
+				}
+
+				scanned := []int{}
+				for _, p := range r.PortStates {
+					scanned = append(scanned, p.Port)
+				}
+				if !reflect.DeepEqual(scanned, tc.expectPorts[i]) {
+					t.Errorf("Expected ports %v for %s, got %v instead\n",
+						tc.expectPorts[i], r.Host, scanned)
+				}
+			}
+		})
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	ports := listenPorts(t, 4)
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+	hl.Add("127.0.0.2")
+
+	defer scan.SetDialLatency(20 * time.Millisecond)()
+
+	testCases := []struct {
+		name      string
+		opts      scan.Options
+		minLength time.Duration
+	}{
+		// 8 connections, 1 every 25ms
+		{"Rate", scan.Options{Rate: 40}, 175 * time.Millisecond},
+		// 4 connections on each address, 1 every 25ms
+		{"HostRate", scan.Options{HostRate: 40}, 75 * time.Millisecond},
+		// 4 connections of 20ms on each address, one at a time
+		{"MaxPerHost", scan.Options{MaxPerHost: 1}, 80 * time.Millisecond},
+		// every connection still takes 20ms
+		{"Randomize", scan.Options{Randomize: true, Jitter: 10 * time.Millisecond},
+			20 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Workers = 8
+			tc.opts.Family = scan.IPv4
+
+			start := time.Now()
+			res := scan.Run(hl, ports, tc.opts)
+			length := time.Since(start)
+
+			if length < tc.minLength {
+				t.Errorf("Expected scan to take at least %s, took %s\n", tc.minLength, length)
+			}
+
+			// limits change when ports are scanned, not the results
+			if len(res) != 2 {
+				t.Fatalf("Expected 2 results, got %d instead\n", len(res))
+			}
+			for i, p := range res[0].PortStates {
+				if p.Port != ports[i] {
+					t.Errorf("Expected port %d at index %d, got %d instead\n", ports[i], i, p.Port)
+				}
+				if i%2 == 0 && p.State != scan.StateOpen {
+					t.Errorf("Expected port %d open, got %s instead\n", p.Port, p.State)
+				}
+			}
+		})
+	}
+}
+
+func TestRunMaxPerHostSpread(t *testing.T) {
+	ports := listenPorts(t, 4)
+
+	hl := &scan.HostsList{}
+	for i := 1; i <= 4; i++ {
+		hl.Add(fmt.Sprintf("127.0.0.%d", i))
+	}
+
+	defer scan.SetDialLatency(50 * time.Millisecond)()
+
+	// 4 connections of 50ms on each address, one at a time, with every
+	// address busy at once. Handing the jobs out address by address would
+	// leave the workers waiting on a single address and take twice as long
+	maxLength := 350 * time.Millisecond
+
+	start := time.Now()
+	res := scan.Run(hl, ports, scan.Options{Workers: 4, MaxPerHost: 1, Family: scan.IPv4})
+	length := time.Since(start)
+
+	if length > maxLength {
+		t.Errorf("Expected scan to take at most %s, took %s\n", maxLength, length)
+	}
+	if len(res) != 4 {
+		t.Fatalf("Expected 4 results, got %d instead\n", len(res))
+	}
+	for _, r := range res {
+		if len(r.PortStates) != len(ports) {
+			t.Errorf("Expected %d ports for %s, got %d instead\n",
+				len(ports), r.Host, len(r.PortStates))
+		}
+	}
+}
+
+func TestRunContextOnPort(t *testing.T) {
+	ports := listenPorts(t, 10)
+
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+	hl.Add("389.389.389.389")
+
+	reported := []scan.PortResult{}
+	res, err := scan.RunContext(context.Background(), hl, ports, scan.Options{
+		Workers: 4,
+		Family:  scan.IPv4,
+		OnPort: func(r scan.PortResult) {
+			reported = append(reported, r)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	if len(reported) != len(ports) {
+		t.Fatalf("Expected %d reported ports, got %d instead\n", len(ports), len(reported))
+	}
+
+	for i, r := range reported {
+		if r.Done != i+1 || r.Total != len(ports) {
+			t.Errorf("Expected progress %d/%d, got %d/%d instead\n",
+				i+1, len(ports), r.Done, r.Total)
+		}
+		if r.Host != "localhost" {
+			t.Errorf("Expected host %q, got %q instead\n", "localhost", r.Host)
+		}
+	}
+
+	if len(res) != 2 || len(res[0].PortStates) != len(ports) {
+		t.Errorf("Expected full results, got %+v instead\n", res)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	ports := listenPorts(t, 20)
+
+	hl := &scan.HostsList{}
+	hl.Add("localhost")
+
+	defer scan.SetDialLatency(20 * time.Millisecond)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := scan.RunContext(ctx, hl, ports, scan.Options{
+		Workers: 2,
+		Family:  scan.IPv4,
+		OnPort: func(r scan.PortResult) {
+			if r.Done == 3 {
+				cancel()
+			}
+		},
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected error %q, got %q instead\n", context.Canceled, err)
+	}
+
+	if len(res) != 1 {
+		t.Fatalf("Expected 1 result, got %d instead\n", len(res))
+	}
+
+	scanned := res[0].PortStates
+	if len(scanned) < 3 || len(scanned) == len(ports) {
+		t.Fatalf("Expected partial results, got %d of %d ports\n",
+			len(scanned), len(ports))
+	}
+
+	// partial results keep the order of the ports and only hold
+	// ports that were actually scanned
+	next := 0
+	for _, p := range scanned {
+		for next < len(ports) && ports[next] != p.Port {
+			next++
+		}
+		if next == len(ports) {
+			t.Fatalf("Port %d out of order in partial results\n", p.Port)
+		}
+		if expOpen := next%2 == 0; (p.State == scan.StateOpen) != expOpen {
+			t.Errorf("Expected port %d open to be %t\n", p.Port, expOpen)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidHost = errors.New("Invalid host")
+
+// maxTargets limits how many addresses a single CIDR block or range
+// expands to, so a typo in a prefix length doesn't start a huge scan
+const maxTargets = 65536
+
+// ValidateHost checks that an entry of the hosts list is a host name,
+// an IPv4 or IPv6 address, a CIDR block (10.0.0.0/28, 2001:db8::/120)
+// or an address range, given either as 10.0.0.1-20 or with the full
+// end address as in 10.0.0.1-10.0.0.20
+func ValidateHost(entry string) error {
+	_, _, err := parseEntry(entry)
+	return err
+}
+
+// parseEntry validates entry and returns the first and last address it
+// covers. Both are zero for host names
+func parseEntry(entry string) (netip.Addr, netip.Addr, error) {
+	none := netip.Addr{}
+
+	if entry == "" {
+		return none, none, fmt.Errorf("%w: empty entry", ErrInvalidHost)
+	}
+
+	if strings.Contains(entry, "/") {
+		return parsePrefix(entry)
+	}
+
+	if strings.Contains(entry, "-") {
+		if start, end, ok := strings.Cut(entry, "-"); ok {
+			if _, err := netip.ParseAddr(start); err == nil {
+				return parseRange(entry, start, end)
+			}
+		}
+	}
+
+	if a, err := netip.ParseAddr(entry); err == nil {
+		return a, a, nil
+	}
+
+	if !validHostname(entry) {
+		return none, none, fmt.Errorf("%w: %q is not a host name, address, CIDR block or range",
+			ErrInvalidHost, entry)
+	}
+
+	return none, none, nil
+}
+
+func parsePrefix(entry string) (netip.Addr, netip.Addr, error) {
+	none := netip.Addr{}
+
+	p, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return none, none, fmt.Errorf("%w: %s", ErrInvalidHost, err)
+	}
+
+	if hostBits := p.Addr().BitLen() - p.Bits(); hostBits > 16 {
+		return none, none, fmt.Errorf("%w: %s expands to more than %d addresses",
+			ErrInvalidHost, entry, maxTargets)
+	}
+
+	first := p.Masked().Addr()
+	last := lastAddr(p)
+
+	// IPv4 blocks don't use their network and broadcast addresses
+	if first.Is4() && p.Bits() <= 30 {
+		first = first.Next()
+		last = last.Prev()
+	}
+
+	return first, last, nil
+}
+
+func parseRange(entry, start, end string) (netip.Addr, netip.Addr, error) {
+	none := netip.Addr{}
+
+	first, err := netip.ParseAddr(start)
+	if err != nil {
+		return none, none, fmt.Errorf("%w: %s: invalid start of range", ErrInvalidHost, entry)
+	}
+
+	// the end of an IPv4 range is either a full address or the last octet
+	if first.Is4() && !strings.Contains(end, ".") {
+		end = start[:strings.LastIndex(start, ".")+1] + end
+	}
+
+	last, err := netip.ParseAddr(end)
+	if err != nil || last.BitLen() != first.BitLen() {
+		return none, none, fmt.Errorf("%w: %s: invalid end of range", ErrInvalidHost, entry)
+	}
+
+	if last.Less(first) {
+		return none, none, fmt.Errorf("%w: %s: range ends before it starts",
+			ErrInvalidHost, entry)
+	}
+
+	if distance(first, last).Cmp(big.NewInt(maxTargets)) >= 0 {
+		return none, none, fmt.Errorf("%w: %s expands to more than %d addresses",
+			ErrInvalidHost, entry, maxTargets)
+	}
+
+	return first, last, nil
+}
+
+// lastAddr returns the last address of the prefix p
+func lastAddr(p netip.Prefix) netip.Addr {
+	a := p.Masked().Addr()
+	b := a.AsSlice()
+
+	for bit := p.Bits(); bit < a.BitLen(); bit++ {
+		b[bit/8] |= 0x80 >> (bit % 8)
+	}
+
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// distance returns how many addresses there are from a to b
+func distance(a, b netip.Addr) *big.Int {
+	x := new(big.Int).SetBytes(a.AsSlice())
+	y := new(big.Int).SetBytes(b.AsSlice())
+	return y.Sub(y, x)
+}
+
+// validHostname checks name against the host name rules of RFC 1123
+func validHostname(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z',
+				c >= '0' && c <= '9', c == '-':
+			default:
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package scan_test
+
+import (
+	"errors"
+	"pScan/scan"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateHost(t *testing.T) {
+	testCases := []struct {
+		entry     string
+		expectErr error
+	}{
+		{"localhost", nil},
+		{"host-1.example.com", nil},
+		{"example.com.", nil},
+		{"192.168.0.199", nil},
+		{"10.0.0.0/28", nil},
+		{"10.0.0.1-20", nil},
+		{"10.0.0.250-10.0.1.5", nil},
+		{"", scan.ErrInvalidHost},
+		{"host_1", scan.ErrInvalidHost},
+		{"exa mple.com", scan.ErrInvalidHost},
+		{"-host", scan.ErrInvalidHost},
+		{"10.0.0.0/33", scan.ErrInvalidHost},
+		{"10.0.0.0/8", scan.ErrInvalidHost},
+		{"10.0.0.1-300", scan.ErrInvalidHost},
+		{"10.0.0.20-1", scan.ErrInvalidHost},
+		{"10.0.0.1-", scan.ErrInvalidHost},
+		{"::1", nil},
+		{"2001:db8::10", nil},
+		{"fe80::1%eth0", nil},
+		{"2001:db8::/120", nil},
+		{"2001:db8::1-2001:db8::ff", nil},
+		{"2001:db8::/64", scan.ErrInvalidHost},
+		{"2001:db8::1-20", scan.ErrInvalidHost},
+		{"2001:db8::1-2001:db9::1", scan.ErrInvalidHost},
+		{"10.0.0.1-2001:db8::1", scan.ErrInvalidHost},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.entry, func(t *testing.T) {
+			err := scan.ValidateHost(tc.entry)
+
+			if tc.expectErr == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %q instead\n", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("Expected error %q, got %q instead\n", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRunEntries(t *testing.T) {
+	defer scan.SetLookup(map[string][]string{"db.lab.test": {"10.0.0.9"}})()
+
+	testCases := []struct {
+		entry  string
+		expect []string
+	}{
+		{"db.lab.test", []string{"db.lab.test"}},
+		{"10.0.0.5", []string{"10.0.0.5"}},
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2"}},
+		{"10.0.0.4/31", []string{"10.0.0.4", "10.0.0.5"}},
+		{"10.0.0.7/32", []string{"10.0.0.7"}},
+		{"10.0.0.1-3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"10.0.0.255-10.0.1.1", []string{"10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{"::1", []string{"::1"}},
+		{"2001:db8::/127", []string{"2001:db8::", "2001:db8::1"}},
+		{"2001:db8::fe/126", []string{"2001:db8::fc", "2001:db8::fd", "2001:db8::fe", "2001:db8::ff"}},
+		{"2001:db8::ffff-2001:db8::1:1", []string{"2001:db8::ffff", "2001:db8::1:0", "2001:db8::1:1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.entry, func(t *testing.T) {
+			if hosts := runEntry(t, tc.entry); !reflect.DeepEqual(hosts, tc.expect) {
+				t.Errorf("Expected hosts %v, got %v instead\n", tc.expect, hosts)
+			}
+		})
+	}
+
+	if hosts := runEntry(t, "10.1.0.0/16"); len(hosts) != 65534 {
+		t.Errorf("Expected 65534 hosts, got %d instead\n", len(hosts))
+	}
+}
+
+// runEntry returns the hosts Run reports for entry. Scanning in the
+// other address family reports them without connecting to any
+func runEntry(t *testing.T, entry string) []string {
+	t.Helper()
+
+	hl := &scan.HostsList{}
+	if err := hl.Add(entry); err != nil {
+		t.Fatalf("Expected no error, got %q instead\n", err)
+	}
+
+	family := scan.IPv6
+	if strings.Contains(entry, ":") {
+		family = scan.IPv4
+	}
+
+	hosts := []string{}
+	for _, r := range scan.Run(hl, []int{1}, scan.Options{Workers: 10, Family: family}) {
+		if !r.NotFound {
+			t.Errorf("Expected %s to be skipped, got %+v instead\n", r.Host, r)
+		}
+		hosts = append(hosts, r.Host)
+	}
+
+	return hosts
+}
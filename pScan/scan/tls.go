@@ -0,0 +1,103 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// TLSInfo describes the TLS session and the certificate found on an
+// open port
+type TLSInfo struct {
+	// Version is the negotiated protocol version, such as TLS 1.3. It's
+	// the newest version both ends speak, the older versions the server
+	// may still accept aren't probed
+	Version string `json:"version"`
+	// ALPN is the application protocol agreed on, if any
+	ALPN      string    `json:"alpn,omitempty"`
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans,omitempty"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// ExpiresWithin reports whether the certificate is expired at now or
+// expires within d of it
+func (t *TLSInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !t.NotAfter.After(now.Add(d))
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+func tlsVersion(v uint16) string {
+	if name, ok := tlsVersions[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// inspectTLS runs a TLS handshake on port of addr and returns what it
+// learned about the session. Ports that don't speak TLS give nil. The
+// certificate isn't verified since expired or self-signed ones are
+// what the inspection is meant to find
+func inspectTLS(ctx context.Context, host, addr string, port int,
+	timeout time.Duration) *TLSInfo {
+
+	address := net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+	conn, err := dialTimeout(ctx, "tcp", address, timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	// TLS 1.0 and 1.1 are off by default, but finding servers that
+	// still speak them is part of the inspection
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS10,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	}
+	// SNI only takes names
+	if _, err := netip.ParseAddr(host); err != nil {
+		cfg.ServerName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil
+	}
+
+	state := tlsConn.ConnectionState()
+	info := &TLSInfo{
+		Version: tlsVersion(state.Version),
+		ALPN:    state.NegotiatedProtocol,
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return info
+	}
+
+	cert := state.PeerCertificates[0]
+	info.Subject = cert.Subject.String()
+	info.Issuer = cert.Issuer.String()
+	info.NotBefore = cert.NotBefore
+	info.NotAfter = cert.NotAfter
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	return info
+}
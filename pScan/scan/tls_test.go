@@ -0,0 +1,137 @@
+package scan_test
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"pScan/scan"
+	"testing"
+	"time"
+)
+
+func TestRunTLS(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+	ts.EnableHTTP2 = true
+	// the connection scanning the port is closed before the handshake
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	tlsPort := ts.Listener.Addr().(*net.TCPAddr).Port
+	plainPort := serve(t, greet("SSH-2.0-OpenSSH_9.3\r\n"))
+	silentPort := serve(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+
+	hl := &scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	res := scan.Run(hl, []int{tlsPort, plainPort, silentPort}, scan.Options{
+		Workers: 3,
+		Timeout: 200 * time.Millisecond,
+		TLS:     true,
+	})
+
+	if len(res) != 1 || len(res[0].PortStates) != 3 {
+		t.Fatalf("Expected 1 host with 3 ports, got %v instead\n", res)
+	}
+
+	info := res[0].PortStates[0].TLS
+	if info == nil {
+		t.Fatalf("Expected TLS details for port %d\n", tlsPort)
+	}
+
+	cert := ts.Certificate()
+	if info.Version != "TLS 1.3" {
+		t.Errorf("Expected version %q, got %q instead\n", "TLS 1.3", info.Version)
+	}
+	if info.ALPN != "h2" {
+		t.Errorf("Expected ALPN %q, got %q instead\n", "h2", info.ALPN)
+	}
+	if info.Subject != cert.Subject.String() || info.Issuer != cert.Issuer.String() {
+		t.Errorf("Expected subject %q and issuer %q, got %q and %q instead\n",
+			cert.Subject, cert.Issuer, info.Subject, info.Issuer)
+	}
+	if !info.NotAfter.Equal(cert.NotAfter) {
+		t.Errorf("Expected expiry %s, got %s instead\n", cert.NotAfter, info.NotAfter)
+	}
+
+	sans := map[string]bool{}
+	for _, s := range info.SANs {
+		sans[s] = true
+	}
+	if !sans["example.com"] || !sans["127.0.0.1"] {
+		t.Errorf("Expected SANs to include example.com and 127.0.0.1, got %q\n", info.SANs)
+	}
+
+	for _, p := range res[0].PortStates[1:] {
+		if p.TLS != nil {
+			t.Errorf("Expected no TLS details for port %d, got %+v\n", p.Port, p.TLS)
+		}
+	}
+}
+
+func TestRunTLSVersions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		version uint16
+		expect  string
+	}{
+		{"TLS10", tls.VersionTLS10, "TLS 1.0"},
+		{"TLS11", tls.VersionTLS11, "TLS 1.1"},
+		{"TLS12", tls.VersionTLS12, "TLS 1.2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+			ts.TLS = &tls.Config{MinVersion: tc.version, MaxVersion: tc.version}
+			ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+			ts.StartTLS()
+			defer ts.Close()
+
+			hl := &scan.HostsList{}
+			hl.Add("127.0.0.1")
+
+			res := scan.Run(hl, []int{ts.Listener.Addr().(*net.TCPAddr).Port}, scan.Options{
+				Workers: 1,
+				Timeout: 200 * time.Millisecond,
+				TLS:     true,
+			})
+
+			info := res[0].PortStates[0].TLS
+			if info == nil {
+				t.Fatalf("Expected TLS details for a %s server\n", tc.expect)
+			}
+			if info.Version != tc.expect {
+				t.Errorf("Expected version %q, got %q instead\n", tc.expect, info.Version)
+			}
+		})
+	}
+}
+
+func TestExpiresWithin(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	day := 24 * time.Hour
+
+	testCases := []struct {
+		name     string
+		notAfter time.Time
+		within   time.Duration
+		expect   bool
+	}{
+		{"Expired", now.Add(-day), 0, true},
+		{"Expiring", now.Add(10 * day), 30 * day, true},
+		{"Valid", now.Add(60 * day), 30 * day, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := &scan.TLSInfo{NotAfter: tc.notAfter}
+			if got := info.ExpiresWithin(now, tc.within); got != tc.expect {
+				t.Errorf("Expected %t, got %t instead\n", tc.expect, got)
+			}
+		})
+	}
+}
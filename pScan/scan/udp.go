@@ -0,0 +1,52 @@
+package scan
+
+// udpServices maps well known UDP ports to their service name
+var udpServices = map[int]string{
+	53:   "domain",
+	67:   "dhcps",
+	68:   "dhcpc",
+	69:   "tftp",
+	123:  "ntp",
+	137:  "netbios-ns",
+	138:  "netbios-dgm",
+	161:  "snmp",
+	162:  "snmptrap",
+	500:  "isakmp",
+	514:  "syslog",
+	520:  "route",
+	1900: "upnp",
+	4500: "nat-t-ike",
+	5353: "mdns",
+}
+
+// dnsProbe is a standard query for the NS records of the root zone,
+// which any DNS server answers, even if only with an error
+var dnsProbe = []byte{
+	0x12, 0x34, // ID
+	0x01, 0x00, // recursion desired
+	0x00, 0x01, // one question
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00,       // root name
+	0x00, 0x02, // type NS
+	0x00, 0x01, // class IN
+}
+
+// ntpProbe is an NTP version 3 client request
+var ntpProbe = func() []byte {
+	b := make([]byte, 48)
+	b[0] = 0x1b
+	return b
+}()
+
+// udpProbe returns the datagram sent to a UDP port. Services that ignore
+// unexpected input get a request in their own protocol, everything else
+// gets an empty datagram
+func udpProbe(port int) []byte {
+	switch port {
+	case 53, 5353:
+		return dnsProbe
+	case 123:
+		return ntpProbe
+	}
+	return []byte{}
+}
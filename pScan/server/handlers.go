@@ -0,0 +1,235 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"pScan/scan"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidData = errors.New("Invalid data")
+
+// hostResponse is a host of the list as the API shows it
+type hostResponse struct {
+	Name string `json:"name"`
+	scan.HostInfo
+}
+
+func replyJSONContent(w http.ResponseWriter, r *http.Request,
+	status int, resp any) {
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+func replyError(w http.ResponseWriter, r *http.Request,
+	status int, message string) {
+
+	log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, message)
+
+	body, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+func replyErr(w http.ResponseWriter, r *http.Request, err error) {
+	replyError(w, r, errStatus(err), err.Error())
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	replyError(w, r, http.StatusMethodNotAllowed, "Method not supported")
+}
+
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		replyError(w, r, http.StatusNotFound, "Not found")
+		return
+	}
+
+	replyJSONContent(w, r, http.StatusOK, struct {
+		Endpoints []string `json:"endpoints"`
+	}{[]string{"/hosts", "/jobs"}})
+}
+
+// hostsRouter serves the hosts list: GET and POST on /hosts, and GET
+// and DELETE on /hosts/<name>
+func (s *Server) hostsRouter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.files.Lock()
+		defer s.files.Unlock()
+
+		hl := &scan.HostsList{}
+		if err := hl.Load(s.cfg.HostsFile); err != nil {
+			replyErr(w, r, err)
+			return
+		}
+
+		if r.URL.Path == "" {
+			switch r.Method {
+			case http.MethodGet:
+				hosts := []hostResponse{}
+				for _, h := range hl.Hosts {
+					hosts = append(hosts, hostResponse{Name: h, HostInfo: hl.Info[h]})
+				}
+				replyJSONContent(w, r, http.StatusOK, struct {
+					Hosts []hostResponse `json:"hosts"`
+				}{hosts})
+			case http.MethodPost:
+				s.addHostHandler(w, r, hl)
+			default:
+				methodNotAllowed(w, r)
+			}
+			return
+		}
+
+		host := scan.Normalize(r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+			for _, h := range hl.Hosts {
+				if h == host {
+					replyJSONContent(w, r, http.StatusOK, hostResponse{Name: h, HostInfo: hl.Info[h]})
+					return
+				}
+			}
+			replyErr(w, r, fmt.Errorf("%w: %s", scan.ErrNotExists, host))
+		case http.MethodDelete:
+			if err := hl.Remove(host); err != nil {
+				replyErr(w, r, err)
+				return
+			}
+			if err := hl.Save(s.cfg.HostsFile); err != nil {
+				replyErr(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, r)
+		}
+	}
+}
+
+func (s *Server) addHostHandler(w http.ResponseWriter, r *http.Request,
+	hl *scan.HostsList) {
+
+	h := hostResponse{}
+	if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
+		replyErr(w, r, fmt.Errorf("%w: invalid JSON: %s", ErrInvalidData, err))
+		return
+	}
+
+	h.Name = scan.Normalize(h.Name)
+	if err := hl.AddWithInfo(h.Name, h.HostInfo); err != nil {
+		replyErr(w, r, err)
+		return
+	}
+
+	if err := hl.Save(s.cfg.HostsFile); err != nil {
+		replyErr(w, r, err)
+		return
+	}
+
+	replyJSONContent(w, r, http.StatusCreated, h)
+}
+
+// jobsRouter serves the scan jobs: GET and POST on /jobs, GET and
+// DELETE on /jobs/<id> and GET on /jobs/<id>/results
+func (s *Server) jobsRouter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" {
+			switch r.Method {
+			case http.MethodGet:
+				jobs := s.jobs.list()
+				// results are fetched job by job
+				for i := range jobs {
+					jobs[i].Results = nil
+				}
+				replyJSONContent(w, r, http.StatusOK, struct {
+					Jobs []Job `json:"jobs"`
+				}{jobs})
+			case http.MethodPost:
+				s.submitHandler(w, r)
+			default:
+				methodNotAllowed(w, r)
+			}
+			return
+		}
+
+		idStr, sub, _ := strings.Cut(r.URL.Path, "/")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			replyErr(w, r, fmt.Errorf("%w: invalid job ID %q", ErrInvalidData, idStr))
+			return
+		}
+
+		switch {
+		case sub == "results" && r.Method == http.MethodGet:
+			j, err := s.jobs.get(id)
+			if err != nil {
+				replyErr(w, r, err)
+				return
+			}
+			if !j.finished() {
+				replyErr(w, r, fmt.Errorf("%w: %d is %s", ErrJobNotFinished, id, j.Status))
+				return
+			}
+			results := j.Results
+			if results == nil {
+				results = []scan.Results{}
+			}
+			replyJSONContent(w, r, http.StatusOK, struct {
+				Results []scan.Results `json:"results"`
+			}{results})
+		case sub != "":
+			replyError(w, r, http.StatusNotFound, "Not found")
+		case r.Method == http.MethodGet:
+			j, err := s.jobs.get(id)
+			if err != nil {
+				replyErr(w, r, err)
+				return
+			}
+			j.Results = nil
+			replyJSONContent(w, r, http.StatusOK, j)
+		case r.Method == http.MethodDelete:
+			if err := s.jobs.cancel(id); err != nil {
+				replyErr(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			methodNotAllowed(w, r)
+		}
+	}
+}
+
+func (s *Server) submitHandler(w http.ResponseWriter, r *http.Request) {
+	req := JobRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		replyErr(w, r, fmt.Errorf("%w: invalid JSON: %s", ErrInvalidData, err))
+		return
+	}
+
+	j, err := s.jobs.submit(req)
+	if err != nil {
+		replyErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.ID))
+	replyJSONContent(w, r, http.StatusAccepted, j)
+}
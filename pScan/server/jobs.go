@@ -0,0 +1,334 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"pScan/scan"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidJob     = errors.New("Invalid job")
+	ErrJobNotFound    = errors.New("Job not found")
+	ErrQueueFull      = errors.New("Job queue full")
+	ErrJobFinished    = errors.New("Job already finished")
+	ErrJobNotFinished = errors.New("Job not finished")
+)
+
+// Job states
+const (
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// maxQueued limits how many jobs can wait to run
+const maxQueued = 100
+
+// JobRequest describes the scan a job runs. Its fields mirror the flags
+// of pScan scan
+type JobRequest struct {
+	// Ports uses the syntax accepted by scan.ParsePorts
+	Ports    []string `json:"ports"`
+	UDP      bool     `json:"udp,omitempty"`
+	Workers  int      `json:"workers,omitempty"`
+	Timeout  string   `json:"timeout,omitempty"`
+	Banners  bool     `json:"banners,omitempty"`
+	TLS      bool     `json:"tls,omitempty"`
+	SkipDown bool     `json:"skip_down,omitempty"`
+	Family   string   `json:"family,omitempty"`
+	Group    string   `json:"group,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Rate, HostRate, MaxPerHost and Jitter limit the connections like
+	// scan.Options does. Jitter uses the syntax of Timeout
+	Rate       float64 `json:"rate,omitempty"`
+	HostRate   float64 `json:"host_rate,omitempty"`
+	MaxPerHost int     `json:"max_per_host,omitempty"`
+	Randomize  bool    `json:"randomize,omitempty"`
+	Jitter     string  `json:"jitter,omitempty"`
+}
+
+// options validates the request and returns the scan it describes
+func (r JobRequest) options() ([]int, scan.Options, error) {
+	opts := scan.Options{
+		Proto:    scan.TCP,
+		Workers:  r.Workers,
+		Banners:  r.Banners,
+		TLS:      r.TLS,
+		SkipDown: r.SkipDown,
+		Family:   r.Family,
+		Group:    r.Group,
+		Tags:     r.Tags,
+
+		Rate:       r.Rate,
+		HostRate:   r.HostRate,
+		MaxPerHost: r.MaxPerHost,
+		Randomize:  r.Randomize,
+	}
+
+	if len(r.Ports) == 0 {
+		return nil, opts, fmt.Errorf("%w: no ports to scan", ErrInvalidJob)
+	}
+
+	ports, err := scan.ParsePorts(r.Ports)
+	if err != nil {
+		return nil, opts, fmt.Errorf("%w: %s", ErrInvalidJob, err)
+	}
+
+	if r.UDP {
+		opts.Proto = scan.UDP
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = 100
+	}
+
+	if r.Timeout != "" {
+		if opts.Timeout, err = time.ParseDuration(r.Timeout); err != nil {
+			return nil, opts, fmt.Errorf("%w: timeout: %s", ErrInvalidJob, err)
+		}
+	}
+
+	if r.Jitter != "" {
+		if opts.Jitter, err = time.ParseDuration(r.Jitter); err != nil {
+			return nil, opts, fmt.Errorf("%w: jitter: %s", ErrInvalidJob, err)
+		}
+	}
+
+	if opts.Rate < 0 || opts.HostRate < 0 || opts.MaxPerHost < 0 || opts.Jitter < 0 {
+		return nil, opts, fmt.Errorf("%w: rates, max_per_host and jitter can't be negative",
+			ErrInvalidJob)
+	}
+
+	switch r.Family {
+	case "", scan.IPv4, scan.IPv6:
+	default:
+		return nil, opts, fmt.Errorf("%w: family %q, use %s or %s",
+			ErrInvalidJob, r.Family, scan.IPv4, scan.IPv6)
+	}
+
+	return ports, opts, nil
+}
+
+// Job is a scan submitted to the server
+type Job struct {
+	ID          int        `json:"id"`
+	Status      string     `json:"status"`
+	Request     JobRequest `json:"request"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	// ScanID is the ID of the scan in the history, when one is kept
+	ScanID  int            `json:"scan_id,omitempty"`
+	Results []scan.Results `json:"results,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// finished reports whether the job won't change anymore
+func (j *Job) finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed ||
+		j.Status == StatusCanceled
+}
+
+// queue holds the jobs of the server. Finished jobs are appended to
+// jobsFile, one JSON record per line like the scan history, so they
+// survive restarts
+type queue struct {
+	mu       sync.Mutex
+	jobs     []*Job
+	byID     map[int]*Job
+	pending  chan *Job
+	jobsFile string
+	// lastID is the highest job ID given so far. Jobs finish, and so
+	// are saved, in any order
+	lastID int
+}
+
+func newQueue(jobsFile string) (*queue, error) {
+	q := &queue{
+		byID:     map[int]*Job{},
+		pending:  make(chan *Job, maxQueued),
+		jobsFile: jobsFile,
+	}
+
+	if jobsFile == "" {
+		return q, nil
+	}
+
+	f, err := os.Open(jobsFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return q, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		j := &Job{}
+		if err := json.Unmarshal(scanner.Bytes(), j); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", jobsFile, line, err)
+		}
+		q.jobs = append(q.jobs, j)
+		q.byID[j.ID] = j
+		if j.ID > q.lastID {
+			q.lastID = j.ID
+		}
+	}
+
+	// list returns the jobs by ID, not by the time they finished
+	sort.SliceStable(q.jobs, func(a, b int) bool {
+		return q.jobs[a].ID < q.jobs[b].ID
+	})
+
+	return q, scanner.Err()
+}
+
+// submit validates r and queues a job to run it
+func (q *queue) submit(r JobRequest) (Job, error) {
+	if _, _, err := r.options(); err != nil {
+		return Job{}, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j := &Job{ID: q.lastID + 1, Status: StatusQueued, Request: r, SubmittedAt: time.Now()}
+
+	select {
+	case q.pending <- j:
+	default:
+		return Job{}, fmt.Errorf("%w: %d jobs waiting", ErrQueueFull, maxQueued)
+	}
+
+	q.lastID = j.ID
+
+	q.jobs = append(q.jobs, j)
+	q.byID[j.ID] = j
+
+	return *j, nil
+}
+
+// get returns a copy of the job, safe to read while it runs
+func (q *queue) get(id int) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.byID[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	return *j, nil
+}
+
+// list returns a copy of all jobs, oldest first
+func (q *queue) list() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// cancel stops a running job, or keeps a queued one from starting
+func (q *queue) cancel(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.byID[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	case j.finished():
+		return fmt.Errorf("%w: %d is %s", ErrJobFinished, id, j.Status)
+	case j.Status == StatusQueued:
+		now := time.Now()
+		j.Status = StatusCanceled
+		j.FinishedAt = &now
+		return q.persist(j)
+	}
+
+	j.cancel()
+	return nil
+}
+
+// start marks a job as running. It returns false for jobs canceled
+// while they were queued
+func (q *queue) start(j *Job, cancel context.CancelFunc) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if j.Status != StatusQueued {
+		return false
+	}
+
+	now := time.Now()
+	j.Status = StatusRunning
+	j.StartedAt = &now
+	j.cancel = cancel
+	return true
+}
+
+// finish records the outcome of a job and saves it to the jobs file
+func (q *queue) finish(j *Job, status string, results []scan.Results,
+	scanID int, err error) error {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	j.Status = status
+	j.FinishedAt = &now
+	j.Results = results
+	j.ScanID = scanID
+	j.cancel = nil
+	if err != nil {
+		j.Error = err.Error()
+	}
+
+	return q.persist(j)
+}
+
+// persist appends a finished job to the jobs file. Callers hold q.mu
+func (q *queue) persist(j *Job) error {
+	if q.jobsFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(q.jobsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,185 @@
+// Package server runs pScan as an HTTP service. It exposes the hosts
+// list and a queue of scan jobs through a JSON API
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"pScan/scan"
+	"sync"
+	"time"
+)
+
+// Config holds the files and limits of a Server
+type Config struct {
+	// HostsFile is the hosts list managed by the API and scanned by jobs
+	HostsFile string
+	// HistoryFile is where finished scans are saved, as pScan scan does.
+	// An empty name disables the history
+	HistoryFile string
+	// JobsFile is where finished jobs are kept across restarts. An
+	// empty name keeps them in memory only
+	JobsFile string
+	// Concurrency is the number of jobs run at the same time. It
+	// defaults to 1
+	Concurrency int
+}
+
+// Server serves the pScan API. Jobs submitted to it wait in a queue
+// until Run picks them up. Jobs still queued or running when the server
+// stops are lost, but finished ones are saved to Config.JobsFile
+type Server struct {
+	cfg  Config
+	jobs *queue
+	// files guards the hosts and history files
+	files sync.Mutex
+}
+
+// New returns a server for cfg, loading the jobs it finished before
+func New(cfg Config) (*Server, error) {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+
+	q, err := newQueue(cfg.JobsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{cfg: cfg, jobs: q}, nil
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	m := http.NewServeMux()
+
+	m.HandleFunc("/", rootHandler)
+
+	h := s.hostsRouter()
+	m.Handle("/hosts", http.StripPrefix("/hosts", h))
+	m.Handle("/hosts/", http.StripPrefix("/hosts/", h))
+
+	j := s.jobsRouter()
+	m.Handle("/jobs", http.StripPrefix("/jobs", j))
+	m.Handle("/jobs/", http.StripPrefix("/jobs/", j))
+
+	return m
+}
+
+// Run runs the queued jobs until ctx is canceled, which cancels the
+// running jobs as well. It returns once they have stopped
+func (s *Server) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < s.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case j := <-s.jobs.pending:
+					s.runJob(ctx, j)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// runJob scans the hosts list as j requests. Jobs canceled while
+// running keep the partial results
+func (s *Server) runJob(ctx context.Context, j *Job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !s.jobs.start(j, cancel) {
+		return
+	}
+
+	// the request was validated when the job was submitted
+	ports, opts, _ := j.Request.options()
+
+	hl, err := s.loadHosts()
+	if err != nil {
+		s.finish(j, StatusFailed, nil, 0, err)
+		return
+	}
+
+	start := time.Now()
+	results, err := scan.RunContext(ctx, hl, ports, opts)
+	if err != nil {
+		s.finish(j, StatusCanceled, results, 0, nil)
+		return
+	}
+
+	scanID, err := s.saveHistory(results, start, time.Now())
+	if err != nil {
+		s.finish(j, StatusFailed, results, 0, err)
+		return
+	}
+
+	s.finish(j, StatusDone, results, scanID, nil)
+}
+
+func (s *Server) finish(j *Job, status string, results []scan.Results,
+	scanID int, jobErr error) {
+
+	if err := s.jobs.finish(j, status, results, scanID, jobErr); err != nil {
+		log.Printf("Job %d: saving to %s: %s", j.ID, s.cfg.JobsFile, err)
+	}
+}
+
+// saveHistory appends a finished scan to the history and returns its
+// ID, or 0 if the history is disabled
+func (s *Server) saveHistory(results []scan.Results, start, end time.Time) (int, error) {
+	if s.cfg.HistoryFile == "" {
+		return 0, nil
+	}
+
+	s.files.Lock()
+	defer s.files.Unlock()
+
+	h := &scan.History{}
+	if err := h.Load(s.cfg.HistoryFile); err != nil {
+		return 0, err
+	}
+
+	r, err := h.Append(s.cfg.HistoryFile, scan.Record{
+		StartedAt:  start,
+		FinishedAt: end,
+		Results:    results,
+	})
+	return r.ID, err
+}
+
+// loadHosts reads the hosts list. Requests changing the list hold the
+// same lock, so a scan never sees a half written file
+func (s *Server) loadHosts() (*scan.HostsList, error) {
+	s.files.Lock()
+	defer s.files.Unlock()
+
+	hl := &scan.HostsList{}
+	return hl, hl.Load(s.cfg.HostsFile)
+}
+
+// errStatus maps the errors of the API to HTTP status codes
+func errStatus(err error) int {
+	switch {
+	case errors.Is(err, scan.ErrInvalidHost), errors.Is(err, scan.ErrInvalidPorts),
+		errors.Is(err, ErrInvalidJob), errors.Is(err, ErrInvalidData):
+		return http.StatusBadRequest
+	case errors.Is(err, scan.ErrNotExists), errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, scan.ErrExists), errors.Is(err, ErrJobFinished),
+		errors.Is(err, ErrJobNotFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,324 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"pScan/scan"
+	"pScan/server"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// setupAPI starts a server on temporary files. Jobs only run if run is
+// set
+func setupAPI(t *testing.T, cfg server.Config, run bool) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg.HostsFile = filepath.Join(dir, "pScan.hosts")
+	if cfg.JobsFile == "" {
+		cfg.JobsFile = filepath.Join(dir, "pScan.jobs")
+	}
+
+	s, err := server.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+
+	if run {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			s.Run(ctx)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+	}
+
+	return ts.URL
+}
+
+// call sends a request with an optional JSON body and decodes the JSON
+// reply into resp, if given
+func call(t *testing.T, method, url, body string, resp any) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	if resp != nil {
+		if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+			t.Fatalf("Decoding %s %s: %s", method, url, err)
+		}
+	}
+
+	return r
+}
+
+func TestHosts(t *testing.T) {
+	url := setupAPI(t, server.Config{}, false)
+
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		expCode int
+	}{
+		{"Add", http.MethodPost, "/hosts", `{"name": "host1", "group": "web", "tags": ["prod"]}`,
+			http.StatusCreated},
+		{"AddCIDR", http.MethodPost, "/hosts", `{"name": "10.0.0.0/30"}`, http.StatusCreated},
+		{"AddExisting", http.MethodPost, "/hosts", `{"name": "host1"}`, http.StatusConflict},
+		{"AddInvalid", http.MethodPost, "/hosts", `{"name": "host_1"}`, http.StatusBadRequest},
+		{"AddInvalidPorts", http.MethodPost, "/hosts", `{"name": "host2", "ports": ["http"]}`,
+			http.StatusBadRequest},
+		{"AddBadJSON", http.MethodPost, "/hosts", `{"name":`, http.StatusBadRequest},
+		{"GetOne", http.MethodGet, "/hosts/host1", "", http.StatusOK},
+		{"GetCIDR", http.MethodGet, "/hosts/10.0.0.0/30", "", http.StatusOK},
+		{"GetMissing", http.MethodGet, "/hosts/host9", "", http.StatusNotFound},
+		{"Delete", http.MethodDelete, "/hosts/10.0.0.0/30", "", http.StatusNoContent},
+		{"DeleteMissing", http.MethodDelete, "/hosts/host9", "", http.StatusNotFound},
+		{"BadMethod", http.MethodPut, "/hosts", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := call(t, tc.method, url+tc.path, tc.body, nil)
+			if r.StatusCode != tc.expCode {
+				t.Errorf("Expected %d, got %d instead\n", tc.expCode, r.StatusCode)
+			}
+		})
+	}
+
+	var list struct {
+		Hosts []struct {
+			Name  string   `json:"name"`
+			Group string   `json:"group"`
+			Tags  []string `json:"tags"`
+		} `json:"hosts"`
+	}
+	call(t, http.MethodGet, url+"/hosts", "", &list)
+
+	if len(list.Hosts) != 1 || list.Hosts[0].Name != "host1" ||
+		list.Hosts[0].Group != "web" || len(list.Hosts[0].Tags) != 1 {
+		t.Errorf("Expected host1 in group web, got %+v instead\n", list.Hosts)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	dir := t.TempDir()
+	cfg := server.Config{
+		HistoryFile: filepath.Join(dir, "pScan.history"),
+		JobsFile:    filepath.Join(dir, "pScan.jobs"),
+	}
+	url := setupAPI(t, cfg, true)
+
+	call(t, http.MethodPost, url+"/hosts", `{"name": "127.0.0.1"}`, nil)
+
+	for _, body := range []string{`{}`, `{"ports": ["0"]}`, `{"ports": ["80"], "timeout": "soon"}`,
+		`{"ports": ["80"], "family": "ipx"}`, `{"ports": ["80"], "rate": -1}`,
+		`{"ports": ["80"], "host_rate": -1}`, `{"ports": ["80"], "max_per_host": -1}`,
+		`{"ports": ["80"], "jitter": "-1s"}`, `{"ports": ["80"], "jitter": "soon"}`} {
+		if r := call(t, http.MethodPost, url+"/jobs", body, nil); r.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %d for %s, got %d instead\n", http.StatusBadRequest, body, r.StatusCode)
+		}
+	}
+
+	job := server.Job{}
+	r := call(t, http.MethodPost, url+"/jobs", fmt.Sprintf(`{"ports": ["%d"]}`, port), &job)
+	if r.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected %d, got %d instead\n", http.StatusAccepted, r.StatusCode)
+	}
+	if loc := r.Header.Get("Location"); loc != fmt.Sprintf("/jobs/%d", job.ID) {
+		t.Errorf("Expected location of job %d, got %q instead\n", job.ID, loc)
+	}
+
+	jobURL := fmt.Sprintf("%s/jobs/%d", url, job.ID)
+	for deadline := time.Now().Add(5 * time.Second); job.Status != server.StatusDone; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected job to finish, got status %q\n", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		call(t, http.MethodGet, jobURL, "", &job)
+	}
+
+	if job.ScanID != 1 {
+		t.Errorf("Expected scan 1 in the history, got %d instead\n", job.ScanID)
+	}
+
+	var results struct {
+		Results []scan.Results `json:"results"`
+	}
+	call(t, http.MethodGet, jobURL+"/results", "", &results)
+
+	if len(results.Results) != 1 || len(results.Results[0].PortStates) != 1 ||
+		results.Results[0].PortStates[0].State != scan.StateOpen {
+		t.Errorf("Expected port %d open, got %+v instead\n", port, results.Results)
+	}
+
+	if r := call(t, http.MethodDelete, jobURL, "", nil); r.StatusCode != http.StatusConflict {
+		t.Errorf("Expected %d canceling a finished job, got %d instead\n",
+			http.StatusConflict, r.StatusCode)
+	}
+
+	for _, path := range []string{"/jobs/99", "/jobs/99/results", "/jobs/1/logs"} {
+		if r := call(t, http.MethodGet, url+path, "", nil); r.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected %d for %s, got %d instead\n", http.StatusNotFound, path, r.StatusCode)
+		}
+	}
+
+	// finished jobs are loaded by the next server
+	url = setupAPI(t, cfg, false)
+
+	var list struct {
+		Jobs []server.Job `json:"jobs"`
+	}
+	call(t, http.MethodGet, url+"/jobs", "", &list)
+
+	if len(list.Jobs) != 1 || list.Jobs[0].ID != job.ID ||
+		list.Jobs[0].Status != server.StatusDone || list.Jobs[0].Results != nil {
+		t.Errorf("Expected finished job %d without results, got %+v instead\n", job.ID, list.Jobs)
+	}
+
+	call(t, http.MethodGet, fmt.Sprintf("%s/jobs/%d/results", url, job.ID), "", &results)
+	if len(results.Results) != 1 {
+		t.Errorf("Expected saved results, got %+v instead\n", results.Results)
+	}
+}
+
+func TestJobLimits(t *testing.T) {
+	ports := []string{}
+	for i := 0; i < 4; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		ports = append(ports, fmt.Sprint(ln.Addr().(*net.TCPAddr).Port))
+	}
+
+	url := setupAPI(t, server.Config{}, true)
+	call(t, http.MethodPost, url+"/hosts", `{"name": "127.0.0.1"}`, nil)
+
+	body := fmt.Sprintf(`{"ports": ["%s"], "family": "ip4", "rate": 20, "host_rate": 20,
+		"max_per_host": 1, "randomize": true, "jitter": "5ms"}`, strings.Join(ports, `", "`))
+
+	job := server.Job{}
+	if r := call(t, http.MethodPost, url+"/jobs", body, &job); r.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected %d, got %d instead\n", http.StatusAccepted, r.StatusCode)
+	}
+
+	jobURL := fmt.Sprintf("%s/jobs/%d", url, job.ID)
+	for deadline := time.Now().Add(5 * time.Second); job.Status != server.StatusDone; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected job to finish, got status %q\n", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		call(t, http.MethodGet, jobURL, "", &job)
+	}
+
+	// 4 connections, 1 every 50ms
+	minLength := 150 * time.Millisecond
+	if length := job.FinishedAt.Sub(*job.StartedAt); length < minLength {
+		t.Errorf("Expected job to take at least %s, took %s\n", minLength, length)
+	}
+	if job.Request.Rate != 20 || job.Request.MaxPerHost != 1 || job.Request.Jitter != "5ms" {
+		t.Errorf("Expected the limits in the request, got %+v instead\n", job.Request)
+	}
+}
+
+func TestJobIDsAfterRestart(t *testing.T) {
+	// jobs are saved as they finish, so a later job can come first
+	jobsFile := filepath.Join(t.TempDir(), "pScan.jobs")
+	saved := `{"id": 3, "status": "done", "request": {"ports": ["80"]}}
+{"id": 1, "status": "done", "request": {"ports": ["80"]}}
+{"id": 2, "status": "canceled", "request": {"ports": ["80"]}}
+`
+	if err := os.WriteFile(jobsFile, []byte(saved), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	url := setupAPI(t, server.Config{JobsFile: jobsFile}, false)
+
+	job := server.Job{}
+	call(t, http.MethodPost, url+"/jobs", `{"ports": ["80"]}`, &job)
+	if job.ID != 4 {
+		t.Errorf("Expected job 4, got %d instead\n", job.ID)
+	}
+
+	var list struct {
+		Jobs []server.Job `json:"jobs"`
+	}
+	call(t, http.MethodGet, url+"/jobs", "", &list)
+
+	ids := []int{}
+	for _, j := range list.Jobs {
+		ids = append(ids, j.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Errorf("Expected jobs [1 2 3 4], got %v instead\n", ids)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	// no jobs run, so they stay queued
+	url := setupAPI(t, server.Config{}, false)
+
+	job := server.Job{}
+	call(t, http.MethodPost, url+"/jobs", `{"ports": ["80"]}`, &job)
+
+	if job.ID != 1 || job.Status != server.StatusQueued {
+		t.Fatalf("Expected job 1 queued, got %+v instead\n", job)
+	}
+
+	jobURL := fmt.Sprintf("%s/jobs/%d", url, job.ID)
+	if r := call(t, http.MethodGet, jobURL+"/results", "", nil); r.StatusCode != http.StatusConflict {
+		t.Errorf("Expected %d for results of a queued job, got %d instead\n",
+			http.StatusConflict, r.StatusCode)
+	}
+
+	if r := call(t, http.MethodDelete, jobURL, "", nil); r.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected %d, got %d instead\n", http.StatusAccepted, r.StatusCode)
+	}
+
+	call(t, http.MethodGet, jobURL, "", &job)
+	if job.Status != server.StatusCanceled {
+		t.Errorf("Expected job canceled, got %q instead\n", job.Status)
+	}
+}